@@ -0,0 +1,199 @@
+package hex
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// WithBearer matches requests with an "Authorization: Bearer <token>" header, where token matches the given string,
+// regular expression, hex.Any, or any other value that matches strings:
+//
+//   WithBearer("abc123")
+//   WithBearer(hex.R(`^ey`))
+//   WithBearer(hex.Any) // any bearer token
+func (e *Expectation) WithBearer(token interface{}) *Expectation {
+	m, err := makeStringMatcher(token)
+	if err != nil {
+		panic(fmt.Sprintf("WithBearer: %s", err.Error()))
+	}
+	return e.addMatcher(&bearerMatcher{token: m})
+}
+
+type bearerMatcher struct {
+	token stringMatcher
+}
+
+var _ matcher = &bearerMatcher{}
+
+func (b *bearerMatcher) matches(req *http.Request) matchResult {
+	token, result := bearerToken(req)
+	if !result.ok {
+		return result
+	}
+	if !b.token.match(token) {
+		return mismatch("got bearer token %q", token)
+	}
+	return matchSuccess
+}
+
+func (b *bearerMatcher) String() string {
+	return fmt.Sprintf("bearer token matching %s", b.token.String())
+}
+
+// bearerToken extracts the token from a request's "Authorization: Bearer <token>" header
+func bearerToken(req *http.Request) (string, matchResult) {
+	auth := req.Header.Get("Authorization")
+	if auth == "" {
+		return "", mismatch("got no Authorization header")
+	}
+
+	scheme, token := auth, ""
+	if space := strings.IndexByte(auth, ' '); space != -1 {
+		scheme, token = auth[:space], strings.TrimSpace(auth[space+1:])
+	}
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", mismatch("got Authorization scheme %q", scheme)
+	}
+	return token, matchSuccess
+}
+
+// WithBasicAuth matches requests using HTTP basic authentication, with a username and password matching the given
+// values. Either may be hex.Any:
+//
+//   WithBasicAuth("admin", "secret")
+//   WithBasicAuth("admin", hex.Any)
+func (e *Expectation) WithBasicAuth(username, password interface{}) *Expectation {
+	usernameMatcher, err := makeStringMatcher(username)
+	if err != nil {
+		panic(fmt.Sprintf("WithBasicAuth: %s", err.Error()))
+	}
+	passwordMatcher, err := makeStringMatcher(password)
+	if err != nil {
+		panic(fmt.Sprintf("WithBasicAuth: %s", err.Error()))
+	}
+	return e.addMatcher(&basicAuthMatcher{username: usernameMatcher, password: passwordMatcher})
+}
+
+type basicAuthMatcher struct {
+	username, password stringMatcher
+}
+
+var _ matcher = &basicAuthMatcher{}
+
+func (b *basicAuthMatcher) matches(req *http.Request) matchResult {
+	username, password, ok := req.BasicAuth()
+	if !ok {
+		auth := req.Header.Get("Authorization")
+		if auth == "" {
+			return mismatch("got no Authorization header")
+		}
+		// Only the scheme is reported, as the rest of the header may hold credentials
+		scheme := strings.SplitN(auth, " ", 2)[0]
+		if strings.EqualFold(scheme, "Basic") {
+			return mismatch("got malformed basic auth credentials")
+		}
+		return mismatch("got Authorization scheme %q", scheme)
+	}
+
+	// Passwords are never reported, so that credentials don't end up in test logs
+	passwordOK := b.password.match(password)
+	if b.username.match(username) && passwordOK {
+		return matchSuccess
+	}
+	if passwordOK {
+		return mismatch("got username %q, password matched", username)
+	}
+	return mismatch("got username %q, password did not match", username)
+}
+
+func (b *basicAuthMatcher) String() string {
+	return fmt.Sprintf("basic auth matching %s:%s", b.username.String(), b.password.String())
+}
+
+// APIKeyLocation is the part of the request in which WithAPIKey looks for an API key
+type APIKeyLocation int
+
+const (
+	// InHeader finds an API key in a request header
+	InHeader APIKeyLocation = 1
+
+	// InQuery finds an API key in a query string parameter
+	InQuery APIKeyLocation = 2
+
+	// InCookie finds an API key in a cookie
+	InCookie APIKeyLocation = 3
+)
+
+func (l APIKeyLocation) String() string {
+	switch l {
+	case InHeader:
+		return "header"
+	case InQuery:
+		return "query string parameter"
+	case InCookie:
+		return "cookie"
+	}
+	return fmt.Sprintf("APIKeyLocation(%d)", int(l))
+}
+
+// WithAPIKey matches requests carrying an API key with the given name, in the header, query string or cookie given by
+// location. The key's value may be anything that matches strings:
+//
+//   WithAPIKey(hex.InHeader, "X-Api-Key", "secret")
+//   WithAPIKey(hex.InQuery, "api_key", hex.R(`^[0-9a-f]{32}$`))
+//   WithAPIKey(hex.InCookie, "session", hex.Any)
+func (e *Expectation) WithAPIKey(location APIKeyLocation, name string, value interface{}) *Expectation {
+	m, err := makeStringMatcher(value)
+	if err != nil {
+		panic(fmt.Sprintf("WithAPIKey: %s", err.Error()))
+	}
+
+	switch location {
+	case InHeader:
+		name = http.CanonicalHeaderKey(name)
+	case InQuery, InCookie:
+	default:
+		panic(fmt.Sprintf("WithAPIKey: invalid location %s", location))
+	}
+
+	return e.addMatcher(&apiKeyMatcher{location: location, name: name, value: m})
+}
+
+type apiKeyMatcher struct {
+	location APIKeyLocation
+	name     string
+	value    stringMatcher
+}
+
+var _ matcher = &apiKeyMatcher{}
+
+func (a *apiKeyMatcher) matches(req *http.Request) matchResult {
+	var values []string
+	switch a.location {
+	case InHeader:
+		values = req.Header[a.name]
+	case InQuery:
+		values = req.URL.Query()[a.name]
+	case InCookie:
+		for _, cookie := range req.Cookies() {
+			if cookie.Name == a.name {
+				values = append(values, cookie.Value)
+			}
+		}
+	}
+
+	if len(values) == 0 {
+		return mismatch("got no %s %s", a.location, a.name)
+	}
+	for _, value := range values {
+		if a.value.match(value) {
+			return matchSuccess
+		}
+	}
+	return mismatch("got %s=%q", a.name, values)
+}
+
+func (a *apiKeyMatcher) String() string {
+	return fmt.Sprintf("API key in %s %s matching %s", a.location, a.name, a.value.String())
+}
@@ -0,0 +1,115 @@
+package hex
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func ExampleExpectation_WithBearer() {
+	e := Expecter{}
+
+	e.ExpectReq("GET", "/users").WithBearer("abc123")
+
+	req := httptest.NewRequest("GET", "/users", nil)
+	req.Header.Set("Authorization", "Bearer xyz789")
+	e.LogReq(req)
+
+	fmt.Println(e.Summary())
+	// Output:
+	// Expectations
+	// 	GET /users with bearer token matching abc123 - failed, no matching requests
+	// 		closest unmatched request: GET /users
+	// 			method GET: passed
+	// 			path /users: passed
+	// 			bearer token matching abc123: failed, got bearer token "xyz789"
+	// Unmatched Requests
+	// 	GET /users
+}
+
+func TestAuthMatchers(t *testing.T) {
+	withHeader := func(key, value string) *http.Request {
+		req := httptest.NewRequest("GET", "/?api_key=q-secret", nil)
+		if key != "" {
+			req.Header.Set(key, value)
+		}
+		return req
+	}
+	withBasicAuth := func(username, password string) *http.Request {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.SetBasicAuth(username, password)
+		return req
+	}
+	withCookie := func(name, value string) *http.Request {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+		return req
+	}
+
+	testCases := []struct {
+		name   string
+		exp    func(*Expectation)
+		req    *http.Request
+		actual string
+	}{
+		{"bearer", func(e *Expectation) { e.WithBearer("abc") }, withHeader("Authorization", "Bearer abc"), ""},
+		{"bearer lowercase scheme", func(e *Expectation) { e.WithBearer("abc") }, withHeader("Authorization", "bearer abc"), ""},
+		{"bearer regexp", func(e *Expectation) { e.WithBearer(R("^a")) }, withHeader("Authorization", "Bearer abc"), ""},
+		{"bearer any", func(e *Expectation) { e.WithBearer(Any) }, withHeader("Authorization", "Bearer abc"), ""},
+		{"bearer missing", func(e *Expectation) { e.WithBearer(Any) }, withHeader("", ""), "got no Authorization header"},
+		{"bearer wrong scheme", func(e *Expectation) { e.WithBearer(Any) }, withBasicAuth("u", "p"), `got Authorization scheme "Basic"`},
+		{"bearer wrong token", func(e *Expectation) { e.WithBearer("abc") }, withHeader("Authorization", "Bearer xyz"), `got bearer token "xyz"`},
+
+		{"basic", func(e *Expectation) { e.WithBasicAuth("admin", "secret") }, withBasicAuth("admin", "secret"), ""},
+		{"basic any password", func(e *Expectation) { e.WithBasicAuth("admin", Any) }, withBasicAuth("admin", "x"), ""},
+		{"basic wrong username", func(e *Expectation) { e.WithBasicAuth("admin", Any) }, withBasicAuth("bob", "x"), `got username "bob", password matched`},
+		{"basic wrong password", func(e *Expectation) { e.WithBasicAuth("admin", "secret") }, withBasicAuth("admin", "x"), `got username "admin", password did not match`},
+		{"basic wrong username and password", func(e *Expectation) { e.WithBasicAuth("admin", "secret") }, withBasicAuth("bob", "x"), `got username "bob", password did not match`},
+		{"basic missing", func(e *Expectation) { e.WithBasicAuth(Any, Any) }, withHeader("", ""), "got no Authorization header"},
+		{"basic wrong scheme", func(e *Expectation) { e.WithBasicAuth(Any, Any) }, withHeader("Authorization", "Bearer abc"), `got Authorization scheme "Bearer"`},
+		{"basic malformed", func(e *Expectation) { e.WithBasicAuth(Any, Any) }, withHeader("Authorization", "Basic !secret"), "got malformed basic auth credentials"},
+
+		{"api key header", func(e *Expectation) { e.WithAPIKey(InHeader, "x-api-key", "secret") }, withHeader("X-Api-Key", "secret"), ""},
+		{"api key header missing", func(e *Expectation) { e.WithAPIKey(InHeader, "X-Api-Key", "secret") }, withHeader("", ""), "got no header X-Api-Key"},
+		{"api key header wrong", func(e *Expectation) { e.WithAPIKey(InHeader, "X-Api-Key", "secret") }, withHeader("X-Api-Key", "x"), `got X-Api-Key=["x"]`},
+		{"api key query", func(e *Expectation) { e.WithAPIKey(InQuery, "api_key", R("^q-")) }, withHeader("", ""), ""},
+		{"api key query missing", func(e *Expectation) { e.WithAPIKey(InQuery, "key", Any) }, withHeader("", ""), "got no query string parameter key"},
+		{"api key cookie", func(e *Expectation) { e.WithAPIKey(InCookie, "session", "s1") }, withCookie("session", "s1"), ""},
+		{"api key cookie wrong", func(e *Expectation) { e.WithAPIKey(InCookie, "session", "s1") }, withCookie("session", "s2"), `got session=["s2"]`},
+		{"api key cookie missing", func(e *Expectation) { e.WithAPIKey(InCookie, "session", "s1") }, withCookie("other", "s1"), "got no cookie session"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := Expecter{}
+			exp := e.ExpectReq("GET", "/")
+			tc.exp(exp)
+			e.LogReq(tc.req)
+
+			if tc.actual == "" {
+				if !e.Pass() {
+					t.Errorf("Expected to pass\n%s", e.Summary())
+				}
+				return
+			}
+			if exp.closest == nil {
+				t.Fatalf("Expected to fail\n%s", e.Summary())
+			}
+			if got := exp.closest.checks[2].actual; got != tc.actual {
+				t.Errorf("Got %q, want %q", got, tc.actual)
+			}
+		})
+	}
+}
+
+func TestWithAPIKeyInvalidLocation(t *testing.T) {
+	defer func() {
+		want := "WithAPIKey: invalid location APIKeyLocation(0)"
+		if got := fmt.Sprint(recover()); got != want {
+			t.Errorf("Got panic %q, want %q", got, want)
+		}
+	}()
+	e := Expecter{}
+	e.ExpectReq("GET", "/").WithAPIKey(0, "key", Any)
+}
@@ -0,0 +1,135 @@
+package hex
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxBodySize is the number of bytes of each request body an Expecter buffers for matching, unless changed
+// with SetMaxBodySize
+const DefaultMaxBodySize int64 = 10 << 20
+
+// SetMaxBodySize sets the maximum number of bytes of each request body that are buffered by LogReq.
+//
+// Buffered bodies can be read any number of times, by every matcher and by every handler that runs after matching.
+// When a body is larger than the limit, matchers and RequestBody only see the first n bytes, and the remainder is
+// streamed to the first handler that reads the body. Values less than or equal to zero restore DefaultMaxBodySize.
+func (e *Expecter) SetMaxBodySize(n int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.maxBodySize = n
+}
+
+// bodyLimit returns the maximum body size. The Expecter's lock must be held.
+func (e *Expecter) bodyLimit() int64 {
+	if e.maxBodySize <= 0 {
+		return DefaultMaxBodySize
+	}
+	return e.maxBodySize
+}
+
+// RequestBody returns the body of a request that has been logged by an Expecter, such as one returned by
+// UnmatchedRequests. Bodies larger than the Expecter's maximum body size are truncated.
+func RequestBody(req *http.Request) []byte {
+	if req.GetBody == nil {
+		return nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// capturedBody holds the buffered portion of a request body
+type capturedBody struct {
+	data []byte
+
+	// rest is the unread remainder of the original body when it was larger than the size limit, or nil
+	rest io.ReadCloser
+}
+
+// captureBody buffers up to limit bytes of the request's body, and replaces req.GetBody so that the buffered bytes
+// can be re-read any number of times. If the body cannot be read, for example because the client aborted an upload,
+// whatever was read before the error is buffered and the error is returned.
+func captureBody(req *http.Request, limit int64) (*capturedBody, error) {
+	c := &capturedBody{}
+
+	var err error
+	if req.Body != nil && req.Body != http.NoBody {
+		var data []byte
+		data, err = io.ReadAll(io.LimitReader(req.Body, limit+1))
+
+		if int64(len(data)) > limit {
+			// Push the byte we read past the limit back in front of the unread remainder
+			c.rest = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(data[limit:]), req.Body), req.Body}
+			data = data[:limit]
+		} else {
+			req.Body.Close()
+		}
+		c.data = data
+	}
+
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(c.data)), nil
+	}
+	rewindBody(req)
+
+	return c, err
+}
+
+// restore replaces the request's body with one that streams the entire original body, including any part beyond the
+// size limit
+func (c *capturedBody) restore(req *http.Request) {
+	if c.rest == nil {
+		rewindBody(req)
+		return
+	}
+
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(c.data), c.rest), c.rest}
+	c.rest = nil
+}
+
+// replayBody records everything read from the request's body, and returns a function that replaces the body with one
+// that streams the entire body again, including any part beyond the size limit that was not buffered
+func replayBody(req *http.Request) func() {
+	body := req.Body
+	if body == nil {
+		return func() {}
+	}
+
+	read := &bytes.Buffer{}
+	// Closing the body here would discard the unread remainder, so it's left for whoever reads the replayed body
+	req.Body = io.NopCloser(io.TeeReader(body, read))
+
+	return func() {
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(read.Bytes()), body), body}
+	}
+}
+
+// rewindBody replaces a request's body with a fresh copy of its buffered body, if it has one
+func rewindBody(req *http.Request) {
+	if req.GetBody == nil {
+		return
+	}
+	if body, err := req.GetBody(); err == nil {
+		req.Body = body
+	}
+}
@@ -8,33 +8,47 @@ import (
 // WithBody adds matching conditions against a request's body.
 // See WithQuery for usage instructions
 func (e *Expectation) WithBody(args ...interface{}) *Expectation {
+	return e.withBody("WithBody", args, false)
+}
+
+// WithExactBody matches against a request's form body like WithBody, but additionally fails if the body contains any
+// keys, or any values for a key, that aren't mentioned by the arguments.
+func (e *Expectation) WithExactBody(args ...interface{}) *Expectation {
+	return e.withBody("WithExactBody", args, true)
+}
+
+func (e *Expectation) withBody(name string, args []interface{}, exact bool) *Expectation {
 	matcher, err := makeURLValuesMatcher(args)
 	if err != nil {
-		panic(fmt.Sprintf("WithBody: %s", err.Error()))
+		panic(fmt.Sprintf("%s: %s", name, err.Error()))
 	}
 
-	e.matchers = append(e.matchers, &bodyMatcher{
+	return e.addMatcher(&bodyMatcher{
 		args:             args,
+		exact:            exact,
 		urlValuesMatcher: matcher,
 	})
-	return e
 }
 
 type bodyMatcher struct {
 	args             []interface{}
+	exact            bool
 	urlValuesMatcher urlValuesMatcher
 }
 
 var _ matcher = &bodyMatcher{}
 
-func (b *bodyMatcher) matches(req *http.Request) bool {
+func (b *bodyMatcher) matches(req *http.Request) matchResult {
 	if err := req.ParseForm(); err != nil {
-		panic("An error occurred while parsing a form")
+		return mismatch("cannot parse form: %s", err.Error())
 	}
 
-	return b.urlValuesMatcher.matches(req.PostForm)
+	return b.urlValuesMatcher.result(req.PostForm, b.exact)
 }
 
 func (b *bodyMatcher) String() string {
+	if b.exact {
+		return fmt.Sprintf("body exactly matching %v", matcherArgsToString(b.args))
+	}
 	return fmt.Sprintf("body matching %v", matcherArgsToString(b.args))
 }
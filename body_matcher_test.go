@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strings"
+	"testing"
 )
 
 func ExampleExpectation_WithBody() {
@@ -25,3 +26,46 @@ func ExampleExpectation_WithBody() {
 	// Expectations
 	// 	POST /posts with body matching title="My first blog post" - passed
 }
+
+func TestExactBodyMatcher(t *testing.T) {
+	testCases := []struct {
+		body string
+		want bool
+	}{
+		{"title=hello", true},
+		{"title=hello&draft=1", false},
+		{"title=hello&title=world", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.body, func(t *testing.T) {
+			e := Expecter{}
+			e.ExpectReq("POST", "/posts").WithExactBody("title", "hello")
+
+			req := httptest.NewRequest("POST", "/posts", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			e.LogReq(req)
+
+			if got := e.Pass(); got != tc.want {
+				t.Errorf("Got %t, want %t\n%s", got, tc.want, e.Summary())
+			}
+		})
+	}
+}
+
+func TestBodyMatcherInvalidForm(t *testing.T) {
+	e := Expecter{}
+	exp := e.ExpectReq("POST", "/x").WithBody("title", "hello")
+
+	req := httptest.NewRequest("POST", "/x?a=%zz", strings.NewReader("title=hello"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	e.LogReq(req)
+
+	if e.Pass() {
+		t.Errorf("Expected a request with an invalid form not to match")
+	}
+	if exp.closest == nil || !strings.HasPrefix(exp.closest.checks[2].actual, "cannot parse form: ") {
+		t.Errorf("Expected the mismatch to include the parse error\n%s", e.Summary())
+	}
+}
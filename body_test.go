@@ -0,0 +1,181 @@
+package hex
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func formRequest(values url.Values) *http.Request {
+	req := httptest.NewRequest("POST", "/users", strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestBodyBuffering(t *testing.T) {
+	t.Run("The body is readable after a body expectation has been matched", func(t *testing.T) {
+		e := Expecter{}
+		e.ExpectReq("POST", "/users").WithBody("name", "bob")
+		req := formRequest(url.Values{"name": {"bob"}})
+		e.LogReq(req)
+
+		body, _ := io.ReadAll(req.Body)
+		if string(body) != "name=bob" {
+			t.Errorf(`Expected body to be "name=bob", got %q`, body)
+		}
+		assertPassed(t, 1, &e)
+	})
+
+	t.Run("Every matcher can read the body", func(t *testing.T) {
+		e := Expecter{}
+		e.ExpectReq("POST", "/users").
+			WithJSONBody(P{"name": "bob"}).
+			WithJSONPath("name", "bob")
+		e.LogReq(httptest.NewRequest("POST", "/users", strings.NewReader(`{"name": "bob"}`)))
+		assertPassed(t, 1, &e)
+	})
+
+	t.Run("The captured body can be inspected after matching", func(t *testing.T) {
+		e := Expecter{}
+		e.ExpectReq("GET", "/status")
+		e.LogReq(httptest.NewRequest("POST", "/users", strings.NewReader(`{"name": "bob"}`)))
+
+		if got := string(RequestBody(e.UnmatchedRequests()[0])); got != `{"name": "bob"}` {
+			t.Errorf(`RequestBody: got %q, want {"name": "bob"}`, got)
+		}
+	})
+
+	t.Run("Requests without a body have an empty captured body", func(t *testing.T) {
+		e := Expecter{}
+		req := httptest.NewRequest("GET", "/status", nil)
+		e.LogReq(req)
+
+		if got := RequestBody(req); len(got) != 0 {
+			t.Errorf("RequestBody: got %q, want empty body", got)
+		}
+	})
+
+	t.Run("Bodies over the size limit are truncated for matchers, but streamed in full to handlers", func(t *testing.T) {
+		e := Expecter{}
+		e.SetMaxBodySize(4)
+		e.ExpectReq("POST", "/users").With(func(req *http.Request) bool {
+			body, _ := io.ReadAll(req.Body)
+			return string(body) == "name"
+		})
+
+		req := httptest.NewRequest("POST", "/users", strings.NewReader("name=bob"))
+		e.LogReq(req)
+		assertPassed(t, 1, &e)
+
+		if got := string(RequestBody(req)); got != "name" {
+			t.Errorf(`RequestBody: got %q, want "name"`, got)
+		}
+
+		body, _ := io.ReadAll(req.Body)
+		if string(body) != "name=bob" {
+			t.Errorf(`Expected body to be "name=bob", got %q`, body)
+		}
+	})
+
+	t.Run("Requests with unreadable bodies are reported as unmatched", func(t *testing.T) {
+		e := Expecter{}
+		e.ExpectReq("POST", "/upload").AtLeast(0)
+
+		body := io.MultiReader(strings.NewReader("part"), iotest.ErrReader(io.ErrUnexpectedEOF))
+		if exp := e.LogReq(httptest.NewRequest("POST", "/upload", body)); exp != nil {
+			t.Errorf("Expected request not to match, but it matched %s", exp)
+		}
+		if len(e.UnmatchedRequests()) != 1 {
+			t.Fatalf("Expected 1 unmatched request, got %d", len(e.UnmatchedRequests()))
+		}
+		if got := string(RequestBody(e.UnmatchedRequests()[0])); got != "part" {
+			t.Errorf(`RequestBody: got %q, want "part"`, got)
+		}
+		if summary := e.Summary(); !strings.Contains(summary, "POST /upload - cannot read body: unexpected EOF") {
+			t.Errorf("Expected summary to include the read error, got %q", summary)
+		}
+	})
+}
+
+func TestServerBodyBuffering(t *testing.T) {
+	readBody := func(req *http.Request) string {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Fatalf("Unexpected error reading body: %s", err)
+		}
+		return string(body)
+	}
+
+	t.Run("The fallback handler can read the body", func(t *testing.T) {
+		var got string
+		server := NewServer(t, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			got = readBody(req)
+		}))
+		server.ExpectReq("POST", "/users").WithBody("name", "bob")
+
+		if _, err := http.PostForm(server.URL+"/users", url.Values{"name": {"bob"}}); err != nil {
+			t.Fatal(err)
+		}
+		if got != "name=bob" {
+			t.Errorf(`Expected fallback handler to read "name=bob", got %q`, got)
+		}
+	})
+
+	t.Run("Mock response handlers and call-through handlers can both read the body", func(t *testing.T) {
+		var gotMock, gotFallback string
+		server := NewServer(t, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			gotFallback = readBody(req)
+		}))
+		server.ExpectReq("POST", "/users").WithBody("name", "bob").RespondWithFn(func(rw http.ResponseWriter, req *http.Request) {
+			gotMock = readBody(req)
+		}).AndCallThrough()
+
+		if _, err := http.PostForm(server.URL+"/users", url.Values{"name": {"bob"}}); err != nil {
+			t.Fatal(err)
+		}
+		if gotMock != "name=bob" || gotFallback != "name=bob" {
+			t.Errorf(`Expected both handlers to read "name=bob", got %q and %q`, gotMock, gotFallback)
+		}
+	})
+
+	t.Run("Call-through handlers read bodies over the size limit in full", func(t *testing.T) {
+		var gotMock, gotFallback string
+		server := NewServer(t, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			gotFallback = readBody(req)
+		}))
+		server.SetMaxBodySize(4)
+		server.ExpectReq("POST", "/users").RespondWithFn(func(rw http.ResponseWriter, req *http.Request) {
+			gotMock = readBody(req)
+		}).AndCallThrough()
+
+		if _, err := http.PostForm(server.URL+"/users", url.Values{"name": {"bob"}}); err != nil {
+			t.Fatal(err)
+		}
+		if gotMock != "name=bob" || gotFallback != "name=bob" {
+			t.Errorf(`Expected both handlers to read "name=bob", got %q and %q`, gotMock, gotFallback)
+		}
+	})
+
+	t.Run("Requests with unreadable bodies receive a 400 response", func(t *testing.T) {
+		called := false
+		server := newUnreportedServer(t)
+		server.handler = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			called = true
+		})
+		server.ExpectReq("POST", "/upload").RespondWith(200, "ok")
+
+		rw := httptest.NewRecorder()
+		server.ServeHTTP(rw, httptest.NewRequest("POST", "/upload", iotest.ErrReader(io.ErrUnexpectedEOF)))
+
+		if rw.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", rw.Code)
+		}
+		if called {
+			t.Errorf("Expected the fallback handler not to be called")
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package hex
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// These tests are most useful when run with the race detector: go test -race ./...
+
+func TestConcurrentServer(t *testing.T) {
+	const numClients = 20
+	const numRequests = 10
+
+	server := NewServer(t, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+	}))
+
+	server.ExpectReq("GET", "/users").WithQuery("page").RespondWith(200, "users")
+	server.ExpectReq("POST", "/users").WithJSONBody(P{"name": Any}).RespondWith(201, "created")
+
+	var wg sync.WaitGroup
+	for i := 0; i < numClients; i++ {
+		wg.Add(1)
+		go func(client int) {
+			defer wg.Done()
+			for j := 0; j < numRequests; j++ {
+				var resp *http.Response
+				var err error
+				switch j % 3 {
+				case 0:
+					resp, err = http.Get(fmt.Sprintf("%s/users?page=%d", server.URL, j))
+				case 1:
+					resp, err = http.Post(server.URL+"/users", "application/json", strings.NewReader(fmt.Sprintf(`{"name": "user%d"}`, client)))
+				default:
+					resp, err = http.Get(server.URL + "/unmatched")
+				}
+				if err != nil {
+					t.Errorf("Unexpected error making request: %s", err)
+					return
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+		}(i)
+	}
+
+	// Inspect the expecter while requests are in flight
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < numRequests; i++ {
+			server.Pass()
+			server.Summary()
+			server.UnmatchedRequests()
+			server.PassedExpectations()
+		}
+	}()
+
+	wg.Wait()
+
+	if got, want := len(server.UnmatchedRequests()), numClients*3; got != want {
+		t.Errorf("len(UnmatchedRequests()): got %d, want %d", got, want)
+	}
+	assertPassed(t, 2, &server.Expecter)
+}
+
+func TestConcurrentExpecter(t *testing.T) {
+	const numGoroutines = 20
+
+	e := Expecter{}
+	e.ExpectReq("GET", "/status")
+
+	var wg sync.WaitGroup
+	for i := 0; i < numGoroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path := fmt.Sprintf("/items/%d", i)
+			e.ExpectReq("GET", path).WithHeader("Accept").RespondWith(200, "ok")
+
+			req := httptest.NewRequest("GET", path, nil)
+			req.Header.Set("Accept", "text/plain")
+			e.LogReq(req)
+			e.LogReq(httptest.NewRequest("GET", "/status", nil))
+			e.SetMaxBodySize(1 << 20)
+			e.FailedExpectations()
+			e.HexReport(&captureT{})
+		}(i)
+	}
+	wg.Wait()
+
+	// Every expectation was made before its matching request was logged, and LogReq always searches the whole
+	// chain of expectations made so far, so everything should have passed
+	assertPassed(t, numGoroutines+1, &e)
+}
+
+func TestConcurrentResponseSequence(t *testing.T) {
+	const numRequests = 20
+
+	server := NewServer(t, nil)
+	exp := server.ExpectReq("GET", "/status").WithDelayRange(0, 20*time.Millisecond)
+	for i := 0; i < numRequests; i++ {
+		if i > 0 {
+			exp.Then()
+		}
+		exp.RespondWith(200, strconv.Itoa(i))
+	}
+
+	var mu sync.Mutex
+	responses := map[string]string{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < numRequests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := strconv.Itoa(i)
+			resp, err := http.Get(server.URL + "/status?id=" + id)
+			if err != nil {
+				t.Errorf("Unexpected error making request: %s", err)
+				return
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+
+			mu.Lock()
+			defer mu.Unlock()
+			responses[id] = string(body)
+		}(i)
+	}
+	wg.Wait()
+
+	// Each request should receive the response at its position in the order requests were matched, regardless of the
+	// order in which their delays finished
+	for i, req := range exp.matches {
+		id := req.URL.Query().Get("id")
+		if got, want := responses[id], strconv.Itoa(i); got != want {
+			t.Errorf("Request %s was matched at position %d: got response %q, want %q", id, i, got, want)
+		}
+	}
+}
+
+func TestMatchFnCanInspectExpecter(t *testing.T) {
+	e := Expecter{}
+	e.ExpectReq("GET", "/status").With(func(req *http.Request) bool {
+		e.Summary()
+		e.UnmatchedRequests()
+		return e.Fail()
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.LogReq(httptest.NewRequest("GET", "/status", nil))
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected LogReq to return, but the MatchFn deadlocked")
+	}
+	assertPassed(t, 1, &e)
+}
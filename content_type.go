@@ -0,0 +1,272 @@
+package hex
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mediaTypeAliases maps the short names accepted by WithContentType and WithAccept to media types. The first media
+// type is used by WithAccept, while WithContentType accepts any of them.
+var mediaTypeAliases = map[string][]string{
+	"json":      {"application/json", "*/*+json"},
+	"xml":       {"application/xml", "text/xml", "*/*+xml"},
+	"html":      {"text/html"},
+	"text":      {"text/plain"},
+	"form":      {"application/x-www-form-urlencoded"},
+	"multipart": {"multipart/form-data", "multipart/*"},
+}
+
+// WithContentType matches the media type of the request's Content-Type header. It accepts a full media type, or one of
+// the aliases json, xml, html, text, form or multipart:
+//
+//   WithContentType("json") // application/json, or any type with a +json suffix
+//   WithContentType("multipart") // multipart/form-data, or any other multipart type
+//   WithContentType("image/*") // any image type
+//   WithContentType("application/json; charset=utf-8") // parameters must be present and equal
+//   WithContentType("multipart", hex.P{"boundary": hex.R(`^-+\w+$`)}) // parameters can be matched like WithQuery
+//
+// Parameters that aren't mentioned are ignored. Media types and charsets are compared case-insensitively.
+func (e *Expectation) WithContentType(mediaType string, params ...P) *Expectation {
+	m, err := makeContentTypeMatcher(mediaType, params)
+	if err != nil {
+		panic(fmt.Sprintf("WithContentType: %s", err.Error()))
+	}
+	return e.addMatcher(m)
+}
+
+type contentTypeMatcher struct {
+	desc   string
+	types  []string
+	params []keyValueMatcher
+}
+
+var _ matcher = &contentTypeMatcher{}
+
+func makeContentTypeMatcher(want string, params []P) (*contentTypeMatcher, error) {
+	m := &contentTypeMatcher{desc: want}
+
+	if types, ok := mediaTypeAliases[strings.ToLower(want)]; ok {
+		m.types = types
+	} else {
+		mediaType, wantParams, err := mime.ParseMediaType(want)
+		if err != nil {
+			return nil, fmt.Errorf("invalid media type %q: %s", want, err.Error())
+		}
+		m.types = []string{mediaType}
+
+		names := make([]string, 0, len(wantParams))
+		for name := range wantParams {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := wantParams[name]
+			var valueMatcher stringMatcher = &stringLiteralMatcher{str: value}
+			if name == "charset" {
+				valueMatcher = &stringFoldMatcher{str: value}
+			}
+			m.params = append(m.params, keyValueMatcher{key: &stringLiteralMatcher{str: name}, value: valueMatcher})
+		}
+	}
+
+	for _, p := range params {
+		names := make([]string, 0, len(p))
+		for key := range p {
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("parameter names must be strings, got %v", key)
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			valueMatcher, err := makeStringMatcher(p[name])
+			if err != nil {
+				return nil, err
+			}
+			m.params = append(m.params, keyValueMatcher{key: &stringLiteralMatcher{str: strings.ToLower(name)}, value: valueMatcher})
+			m.desc += fmt.Sprintf("; %s=%s", name, valueMatcher.String())
+		}
+	}
+
+	return m, nil
+}
+
+func (c *contentTypeMatcher) matches(req *http.Request) matchResult {
+	header := req.Header.Get("Content-Type")
+	if header == "" {
+		return mismatch("got no Content-Type header")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		return mismatch("got invalid Content-Type %q", header)
+	}
+
+	matched := false
+	for _, t := range c.types {
+		if mediaTypeMatches(t, mediaType) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return mismatch("got %q", header)
+	}
+
+	for _, param := range c.params {
+		name := param.key.String()
+		value, ok := params[name]
+		if !ok {
+			return mismatch("got no %s parameter in %q", name, header)
+		}
+		if !param.value.match(value) {
+			return mismatch("got %s=%q in %q", name, value, header)
+		}
+	}
+
+	return matchSuccess
+}
+
+func (c *contentTypeMatcher) String() string {
+	return fmt.Sprintf("content type %s", c.desc)
+}
+
+// mediaTypeMatches returns true if mediaType matches pattern, which may be a full media type, a wildcard like image/*
+// or */*, or a structured syntax suffix like */*+json
+func mediaTypeMatches(pattern, mediaType string) bool {
+	pattern, mediaType = strings.ToLower(pattern), strings.ToLower(mediaType)
+	if pattern == mediaType || pattern == "*/*" {
+		return true
+	}
+
+	if strings.HasPrefix(pattern, "*/*+") {
+		return strings.HasSuffix(mediaType, pattern[3:])
+	}
+
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(mediaType, pattern[:len(pattern)-1])
+	}
+	return false
+}
+
+// WithAccept matches the request's Accept header. Each of the given media types (or aliases, see WithContentType) must
+// be acceptable, and each must be strictly preferred over the next according to its q-value:
+//
+//   WithAccept("json") // application/json is acceptable
+//   WithAccept("json", "xml") // application/json is preferred over application/xml
+//
+// The quality of each media type is taken from the most specific matching range in the header, so
+// "application/*;q=0.5, application/json" prefers application/json over application/xml.
+func (e *Expectation) WithAccept(mediaTypes ...string) *Expectation {
+	if len(mediaTypes) == 0 {
+		panic("WithAccept: at least one media type is required")
+	}
+
+	m := &acceptMatcher{}
+	for _, want := range mediaTypes {
+		if types, ok := mediaTypeAliases[strings.ToLower(want)]; ok {
+			want = types[0]
+		} else if mediaType, _, err := mime.ParseMediaType(want); err != nil {
+			panic(fmt.Sprintf("WithAccept: invalid media type %q: %s", want, err.Error()))
+		} else {
+			want = mediaType
+		}
+		m.types = append(m.types, want)
+	}
+	return e.addMatcher(m)
+}
+
+type acceptMatcher struct {
+	types []string
+}
+
+var _ matcher = &acceptMatcher{}
+
+func (a *acceptMatcher) matches(req *http.Request) matchResult {
+	header := strings.Join(req.Header.Values("Accept"), ", ")
+	if header == "" {
+		return mismatch("got no Accept header")
+	}
+
+	ranges := parseAccept(header)
+	qualities := make([]float64, len(a.types))
+	for i, t := range a.types {
+		qualities[i] = acceptQuality(ranges, t)
+		if qualities[i] == 0 {
+			return mismatch("got %s not acceptable in %q", t, header)
+		}
+	}
+
+	for i := 1; i < len(a.types); i++ {
+		if qualities[i-1] <= qualities[i] {
+			return mismatch("got %s (q=%s) not preferred over %s (q=%s) in %q", a.types[i-1], formatQuality(qualities[i-1]),
+				a.types[i], formatQuality(qualities[i]), header)
+		}
+	}
+
+	return matchSuccess
+}
+
+func (a *acceptMatcher) String() string {
+	if len(a.types) == 1 {
+		return fmt.Sprintf("accepting %s", a.types[0])
+	}
+	return fmt.Sprintf("accepting %s in order of preference", strings.Join(a.types, ", "))
+}
+
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+// parseAccept parses the media ranges of an Accept header, ignoring any that are malformed
+func parseAccept(header string) (ranges []acceptRange) {
+	for _, part := range strings.Split(header, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil || quality < 0 || quality > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, quality: quality})
+	}
+	return
+}
+
+// acceptQuality returns the q-value given to mediaType by the most specific matching range, or 0 if no range matches
+func acceptQuality(ranges []acceptRange, mediaType string) float64 {
+	best, quality := -1, 0.0
+	for _, r := range ranges {
+		if !mediaTypeMatches(r.mediaType, mediaType) {
+			continue
+		}
+		// Exact types are more specific than type/*, which is more specific than */*
+		specificity := 2
+		if r.mediaType == "*/*" {
+			specificity = 0
+		} else if strings.HasSuffix(r.mediaType, "/*") {
+			specificity = 1
+		}
+		if specificity > best {
+			best, quality = specificity, r.quality
+		}
+	}
+	return quality
+}
+
+func formatQuality(q float64) string {
+	return strconv.FormatFloat(q, 'f', -1, 64)
+}
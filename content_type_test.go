@@ -0,0 +1,151 @@
+package hex
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+)
+
+func ExampleExpectation_WithAccept() {
+	e := Expecter{}
+
+	e.ExpectReq("GET", "/users").WithAccept("json", "xml")
+
+	req := httptest.NewRequest("GET", "/users", nil)
+	req.Header.Set("Accept", "application/xml, application/json;q=0.9")
+	e.LogReq(req)
+
+	fmt.Println(e.Summary())
+	// Output:
+	// Expectations
+	// 	GET /users with accepting application/json, application/xml in order of preference - failed, no matching requests
+	// 		closest unmatched request: GET /users
+	// 			method GET: passed
+	// 			path /users: passed
+	// 			accepting application/json, application/xml in order of preference: failed, got application/json (q=0.9) not preferred over application/xml (q=1) in "application/xml, application/json;q=0.9"
+	// Unmatched Requests
+	// 	GET /users
+}
+
+func TestContentTypeMatcher(t *testing.T) {
+	testCases := []struct {
+		want        string
+		params      []P
+		contentType string
+		actual      string
+	}{
+		{"json", nil, "application/json", ""},
+		{"json", nil, "Application/JSON; charset=utf-8", ""},
+		{"json", nil, "application/vnd.api+json", ""},
+		{"json", nil, "application/xml", `got "application/xml"`},
+		{"json", nil, "", "got no Content-Type header"},
+		{"json", nil, "application/", `got invalid Content-Type "application/"`},
+		{"xml", nil, "text/xml", ""},
+		{"xml", nil, "application/atom+xml", ""},
+		{"form", nil, "application/x-www-form-urlencoded", ""},
+		{"multipart", nil, "multipart/form-data; boundary=abc", ""},
+		{"multipart", nil, "multipart/mixed; boundary=abc", ""},
+		{"text", nil, "text/plain", ""},
+		{"text", nil, "text/html", `got "text/html"`},
+		{"html", nil, "text/html", ""},
+		{"image/*", nil, "image/png", ""},
+		{"image/*", nil, "text/png", `got "text/png"`},
+		{"application/json", nil, "application/json; charset=utf-8", ""},
+		{"application/json; charset=utf-8", nil, "application/json; charset=UTF-8", ""},
+		{"application/json; charset=utf-8", nil, "application/json", `got no charset parameter in "application/json"`},
+		{"application/json; charset=utf-8", nil, "application/json; charset=latin1", `got charset="latin1" in "application/json; charset=latin1"`},
+		{"multipart", []P{{"boundary": Any}}, "multipart/form-data; boundary=abc", ""},
+		{"multipart", []P{{"boundary": R(`^x`)}}, "multipart/form-data; boundary=abc", `got boundary="abc" in "multipart/form-data; boundary=abc"`},
+		{"multipart", []P{{"Boundary": Any}}, "multipart/form-data", `got no boundary parameter in "multipart/form-data"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s %s", tc.want, tc.contentType), func(t *testing.T) {
+			e := Expecter{}
+			exp := e.ExpectReq("POST", "/").WithContentType(tc.want, tc.params...)
+
+			req := httptest.NewRequest("POST", "/", nil)
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			e.LogReq(req)
+
+			if tc.actual == "" {
+				if !e.Pass() {
+					t.Errorf("Expected to pass\n%s", e.Summary())
+				}
+				return
+			}
+			if exp.closest == nil {
+				t.Fatalf("Expected to fail\n%s", e.Summary())
+			}
+			if got := exp.closest.checks[2].actual; got != tc.actual {
+				t.Errorf("Got %q, want %q", got, tc.actual)
+			}
+		})
+	}
+}
+
+func TestContentTypeMatcherString(t *testing.T) {
+	m, err := makeContentTypeMatcher("multipart", []P{{"boundary": Any, "charset": "utf-8"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.String(), "content type multipart; boundary=<any>; charset=utf-8"; got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+
+	if _, err := makeContentTypeMatcher("not a media type", nil); err == nil {
+		t.Errorf("Expected an error for an invalid media type")
+	}
+}
+
+func TestAcceptMatcher(t *testing.T) {
+	testCases := []struct {
+		want   []string
+		accept string
+		actual string
+	}{
+		{[]string{"json"}, "application/json", ""},
+		{[]string{"json"}, "*/*", ""},
+		{[]string{"json"}, "application/*", ""},
+		{[]string{"json"}, "text/html", `got application/json not acceptable in "text/html"`},
+		{[]string{"json"}, "application/json;q=0", `got application/json not acceptable in "application/json;q=0"`},
+		{[]string{"json"}, "", "got no Accept header"},
+		{[]string{"json", "xml"}, "application/json, application/xml;q=0.9", ""},
+		{[]string{"json", "xml"}, "application/xml;q=0.5, application/json", ""},
+		{[]string{"json", "xml"}, "application/json, application/xml", `got application/json (q=1) not preferred over application/xml (q=1) in "application/json, application/xml"`},
+		{[]string{"json", "xml"}, "application/json", `got application/xml not acceptable in "application/json"`},
+		{[]string{"json", "xml"}, "application/*;q=0.5, application/json", ""},
+		{[]string{"json", "xml"}, "application/json;q=0.8, */*;q=0.1", ""},
+		{[]string{"json", "xml", "text/plain"}, "application/json, application/xml;q=0.9, */*;q=0.1", ""},
+		{[]string{"application/json", "html"}, "text/html;q=0.2, application/json;q=0.7", ""},
+		{[]string{"json"}, "application/json;q=abc, application/json;q=0.5", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%v %s", tc.want, tc.accept), func(t *testing.T) {
+			e := Expecter{}
+			exp := e.ExpectReq("GET", "/").WithAccept(tc.want...)
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			e.LogReq(req)
+
+			if tc.actual == "" {
+				if !e.Pass() {
+					t.Errorf("Expected to pass\n%s", e.Summary())
+				}
+				return
+			}
+			if exp.closest == nil {
+				t.Fatalf("Expected to fail\n%s", e.Summary())
+			}
+			if got := exp.closest.checks[2].actual; got != tc.actual {
+				t.Errorf("Got %q, want %q", got, tc.actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package hex
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// WithDelay delays the response to each matching request by d, simulating a slow server's time to first byte. The
+// delay applies to any mock response, or to the original handler if the expectation has no mock response.
+//
+//   server.ExpectReq("GET", "/slow").WithDelay(2 * time.Second).RespondWith(200, "ok")
+//
+// If the client gives up while waiting, for example because its request timed out, the response is abandoned and the
+// summary records that the client cancelled the request. Cancellation does not fail the expectation.
+func (e *Expectation) WithDelay(d time.Duration) *Expectation {
+	return e.WithDelayRange(d, d)
+}
+
+// WithDelayRange is like WithDelay, but delays each response by a random duration between min and max
+func (e *Expectation) WithDelayRange(min, max time.Duration) *Expectation {
+	if min < 0 || max < min {
+		panic(fmt.Sprintf("WithDelayRange: invalid range %s..%s", min, max))
+	}
+	defer e.lock()()
+	e.delayMin, e.delayMax = min, max
+	return e
+}
+
+// WithChunkDelay delays each write of the response body after the first by d, and flushes each write to the client,
+// simulating a server that streams its response slowly. It can be combined with WithDelay to control both the time to
+// first byte and the time between chunks.
+//
+//   server.ExpectReq("GET", "/export").WithChunkDelay(100 * time.Millisecond).RespondWithFn(...)
+//
+// Like WithDelay, the delay is abandoned if the client gives up, and the remainder of the response is discarded.
+func (e *Expectation) WithChunkDelay(d time.Duration) *Expectation {
+	if d < 0 {
+		panic(fmt.Sprintf("WithChunkDelay: invalid delay %s", d))
+	}
+	defer e.lock()()
+	e.chunkDelay = d
+	return e
+}
+
+// delay waits for the expectation's time to first byte, and returns the writer the response should be written to. It
+// returns false if the client cancelled the request while waiting, in which case nothing should be written.
+func (e *Expectation) delay(rw http.ResponseWriter, req *http.Request) (http.ResponseWriter, bool) {
+	e.expecter.mu.Lock()
+	d := e.delayMin
+	if e.delayMax > e.delayMin {
+		d += time.Duration(rand.Int63n(int64(e.delayMax - e.delayMin)))
+	}
+	chunkDelay := e.chunkDelay
+	e.expecter.mu.Unlock()
+
+	if !e.wait(req, d) {
+		return nil, false
+	}
+
+	if chunkDelay > 0 {
+		rw = &chunkDelayWriter{ResponseWriter: rw, exp: e, req: req, delay: chunkDelay}
+	}
+	return rw, true
+}
+
+// wait sleeps for d, or until the request's context is done. It returns false, and records the cancellation, if the
+// context was done first.
+func (e *Expectation) wait(req *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-req.Context().Done():
+		return e.recordCancellation(req)
+	}
+}
+
+// recordCancellation notes that the client gave up on req while it was delayed. It always returns false.
+func (e *Expectation) recordCancellation(req *http.Request) bool {
+	defer e.lock()()
+	e.cancelled = append(e.cancelled, fmt.Sprintf("%s %s", req.Method, req.URL.Path))
+	return false
+}
+
+// chunkDelayWriter delays and flushes each write after the first, see WithChunkDelay
+type chunkDelayWriter struct {
+	http.ResponseWriter
+	exp   *Expectation
+	req   *http.Request
+	delay time.Duration
+
+	written   bool
+	cancelled bool
+}
+
+var _ http.Flusher = &chunkDelayWriter{}
+
+func (c *chunkDelayWriter) Write(data []byte) (int, error) {
+	if c.cancelled {
+		return len(data), nil
+	}
+
+	if c.written && !c.exp.wait(c.req, c.delay) {
+		// The client is gone, so discard the rest of the response rather than failing the handler's writes
+		c.cancelled = true
+		return len(data), nil
+	}
+	c.written = true
+
+	n, err := c.ResponseWriter.Write(data)
+	c.Flush()
+	return n, err
+}
+
+// Flush sends any buffered data to the client, if the underlying ResponseWriter supports it
+func (c *chunkDelayWriter) Flush() {
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
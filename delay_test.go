@@ -0,0 +1,166 @@
+package hex
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithDelay(t *testing.T) {
+	t.Run("Delays the response", func(t *testing.T) {
+		s := NewServer(t, nil)
+		s.ExpectReq("GET", "/slow").WithDelay(50*time.Millisecond).RespondWith(200, "ok")
+
+		start := time.Now()
+		rec := serve(s, "GET", "/slow")
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Errorf("Expected response to be delayed by 50ms, took %s", elapsed)
+		}
+		if rec.Body.String() != "ok" {
+			t.Errorf("Body: got %q, want %q", rec.Body.String(), "ok")
+		}
+	})
+
+	t.Run("Delays the original handler when there is no mock response", func(t *testing.T) {
+		s := NewServer(t, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			io.WriteString(rw, "original")
+		}))
+		s.ExpectReq("GET", "/slow").WithDelay(50 * time.Millisecond)
+
+		start := time.Now()
+		if got := serve(s, "GET", "/slow").Body.String(); got != "original" {
+			t.Errorf("Body: got %q, want %q", got, "original")
+		}
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Errorf("Expected response to be delayed by 50ms, took %s", elapsed)
+		}
+	})
+
+	t.Run("Stops waiting when the client gives up", func(t *testing.T) {
+		s := &Server{}
+		s.ExpectReq("GET", "/slow").WithDelay(time.Minute).RespondWith(200, "ok")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		rec := httptest.NewRecorder()
+		start := time.Now()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", "/slow", nil).WithContext(ctx))
+		if elapsed := time.Since(start); elapsed > 10*time.Second {
+			t.Errorf("Expected handler to return when the client gave up, took %s", elapsed)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("Expected no response to be written, got %q", rec.Body.String())
+		}
+
+		if !s.Pass() {
+			t.Errorf("Expected cancellation not to fail the expectation")
+		}
+		if summary := s.Summary(); !strings.Contains(summary, "client cancelled GET /slow while delayed") {
+			t.Errorf("Expected summary to record the cancellation, got %q", summary)
+		}
+	})
+
+	t.Run("Works with a real client timeout", func(t *testing.T) {
+		s := NewServer(t, nil)
+		s.ExpectReq("GET", "/slow").WithDelay(time.Minute).RespondWith(200, "ok")
+
+		client := &http.Client{Timeout: 20 * time.Millisecond}
+		if resp, err := client.Get(s.URL + "/slow"); err == nil {
+			resp.Body.Close()
+			t.Fatalf("Expected the client to time out")
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(s.Summary(), "client cancelled") {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected summary to record the cancellation, got %q", s.Summary())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+}
+
+func TestWithDelayRange(t *testing.T) {
+	s := NewServer(t, nil)
+	s.ExpectReq("GET", "/jitter").WithDelayRange(10*time.Millisecond, 30*time.Millisecond).RespondWith(200, "ok").AtLeast(1)
+
+	for i := 0; i < 5; i++ {
+		start := time.Now()
+		serve(s, "GET", "/jitter")
+		if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+			t.Errorf("Expected response to be delayed by at least 10ms, took %s", elapsed)
+		}
+	}
+
+	t.Run("Invalid ranges panic", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil || !strings.HasPrefix(r.(string), "WithDelayRange: ") {
+				t.Errorf("Expected WithDelayRange to panic, got %v", r)
+			}
+		}()
+		(&Server{}).ExpectReq("GET", "/").WithDelayRange(time.Second, time.Millisecond)
+	})
+}
+
+func TestWithChunkDelay(t *testing.T) {
+	chunks := func(rw http.ResponseWriter, req *http.Request) {
+		for _, chunk := range []string{"a", "b", "c"} {
+			io.WriteString(rw, chunk)
+		}
+	}
+
+	t.Run("Delays and flushes each chunk after the first", func(t *testing.T) {
+		s := NewServer(t, nil)
+		s.ExpectReq("GET", "/stream").WithChunkDelay(20 * time.Millisecond).RespondWithFn(chunks)
+
+		start := time.Now()
+		rec := serve(s, "GET", "/stream")
+		if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+			t.Errorf("Expected 2 delays of 20ms, took %s", elapsed)
+		}
+		if rec.Body.String() != "abc" {
+			t.Errorf("Body: got %q, want %q", rec.Body.String(), "abc")
+		}
+		if !rec.Flushed {
+			t.Errorf("Expected chunks to be flushed")
+		}
+	})
+
+	t.Run("Handlers can flush", func(t *testing.T) {
+		s := NewServer(t, nil)
+		s.ExpectReq("GET", "/stream").WithChunkDelay(time.Millisecond).RespondWithFn(func(rw http.ResponseWriter, req *http.Request) {
+			flusher, ok := rw.(http.Flusher)
+			if !ok {
+				t.Fatalf("Expected the ResponseWriter to implement http.Flusher")
+			}
+			io.WriteString(rw, "a")
+			flusher.Flush()
+		})
+
+		if rec := serve(s, "GET", "/stream"); !rec.Flushed {
+			t.Errorf("Expected the response to be flushed")
+		}
+	})
+
+	t.Run("Discards the rest of the response when the client gives up", func(t *testing.T) {
+		s := &Server{}
+		s.ExpectReq("GET", "/stream").WithChunkDelay(time.Minute).RespondWithFn(chunks)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", "/stream", nil).WithContext(ctx))
+		if rec.Body.String() != "a" {
+			t.Errorf("Body: got %q, want %q", rec.Body.String(), "a")
+		}
+		if summary := s.Summary(); !strings.Contains(summary, "client cancelled GET /stream while delayed") {
+			t.Errorf("Expected summary to record the cancellation, got %q", summary)
+		}
+	})
+}
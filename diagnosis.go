@@ -0,0 +1,92 @@
+package hex
+
+import (
+	"net/http"
+)
+
+// diagnosis records how closely a request came to matching an expectation, with the result of each individual check.
+// When an expectation fails, the summary includes the diagnosis of the unmatched request that came closest to
+// matching it.
+type diagnosis struct {
+	exp     *Expectation
+	req     *http.Request
+	checks  []diagnosisCheck
+	skipped bool
+}
+
+type diagnosisCheck struct {
+	desc string
+	matchResult
+}
+
+// evaluate tests the request against the expectation's method, path and the given matchers, which are the
+// expectation's matchers when the request was logged. It's called without the Expecter's lock held, as matchers may
+// run user code, such as a MatchFn, which calls back into the Expecter.
+func (e *Expectation) evaluate(req *http.Request, matchers []matcher) *diagnosis {
+	d := &diagnosis{exp: e, req: req}
+	d.add("method "+e.method.String(), matchString(e.method, req.Method))
+	d.add("path "+e.path.String(), matchString(e.path, req.URL.Path))
+
+	// Requests with the wrong method or path aren't worth comparing further, and custom matchers may not expect them
+	if d.score() < 2 {
+		d.skipped = true
+		return d
+	}
+
+	for _, m := range matchers {
+		// Give each matcher a fresh copy of the buffered body
+		rewindBody(req)
+		d.add(m.String(), m.matches(req))
+	}
+	return d
+}
+
+func matchString(m stringMatcher, candidate string) matchResult {
+	if m.match(candidate) {
+		return matchSuccess
+	}
+	return mismatch("got %q", candidate)
+}
+
+func (d *diagnosis) add(desc string, result matchResult) {
+	d.checks = append(d.checks, diagnosisCheck{desc: desc, matchResult: result})
+}
+
+// pass returns true if every check passed
+func (d *diagnosis) pass() bool {
+	return !d.skipped && d.score() == len(d.checks)
+}
+
+// score is the number of checks that passed
+func (d *diagnosis) score() (n int) {
+	for _, c := range d.checks {
+		if c.ok {
+			n++
+		}
+	}
+	return
+}
+
+// considerClosest records the diagnosis of an unmatched request if it came closer to matching the expectation than
+// any previous unmatched request. The Expecter's lock must be held.
+func (e *Expectation) considerClosest(d *diagnosis) {
+	if d.score() == 0 {
+		return
+	}
+	if e.closest == nil || d.score() > e.closest.score() {
+		e.closest = d
+	}
+}
+
+// write logs the diagnosis as part of a summary
+func (d *diagnosis) write(t TestingT) {
+	t.Helper()
+	t.Logf("\t\tclosest unmatched request: %s %s\n", d.req.Method, d.req.URL.Path)
+	for _, c := range d.checks {
+		if c.ok {
+			t.Logf("\t\t\t%s: passed\n", c.desc)
+		} else {
+			t.Logf("\t\t\t%s: failed, %s\n", c.desc, c.actual)
+		}
+	}
+}
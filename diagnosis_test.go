@@ -0,0 +1,137 @@
+package hex
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func ExampleExpecter_Summary_closestMatch() {
+	e := Expecter{}
+
+	e.ExpectReq("GET", "/users").
+		WithHeader("Authorization", R("^Bearer .+$")).
+		WithQuery("page", "2")
+
+	req := httptest.NewRequest("GET", "/users?page=2", nil)
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	e.LogReq(req)
+
+	fmt.Println(e.Summary())
+	// Output:
+	// Expectations
+	// 	GET /users with header matching Authorization="^Bearer .+$"query string matching page="2" - failed, no matching requests
+	// 		closest unmatched request: GET /users
+	// 			method GET: passed
+	// 			path /users: passed
+	// 			header matching Authorization="^Bearer .+$": failed, got Authorization=["Basic dXNlcjpwYXNz"]
+	// 			query string matching page="2": passed
+	// Unmatched Requests
+	// 	GET /users
+}
+
+func TestClosestMatch(t *testing.T) {
+	t.Run("The request matching the most conditions is chosen", func(t *testing.T) {
+		e := Expecter{}
+		exp := e.ExpectReq("POST", "/users").WithQuery("a", "1").WithQuery("b", "2")
+
+		e.LogReq(httptest.NewRequest("GET", "/users?a=1", nil))
+		e.LogReq(httptest.NewRequest("POST", "/users?a=1", nil))
+		e.LogReq(httptest.NewRequest("POST", "/users", nil))
+
+		if exp.closest == nil {
+			t.Fatalf("Expected a closest match to be recorded")
+		}
+		if got := exp.closest.req.URL.RawQuery; got != "a=1" {
+			t.Errorf("Expected the closest request to be POST /users?a=1, got %s", got)
+		}
+	})
+
+	t.Run("The earliest of several equally close requests is chosen", func(t *testing.T) {
+		e := Expecter{}
+		exp := e.ExpectReq("POST", "/users")
+
+		e.LogReq(httptest.NewRequest("GET", "/users?first", nil))
+		e.LogReq(httptest.NewRequest("GET", "/users?second", nil))
+
+		if exp.closest == nil || exp.closest.req.URL.RawQuery != "first" {
+			t.Errorf("Expected the first request to be chosen as the closest match")
+		}
+	})
+
+	t.Run("Requests matching neither method nor path are not reported", func(t *testing.T) {
+		e := Expecter{}
+		exp := e.ExpectReq("POST", "/users")
+		e.LogReq(httptest.NewRequest("GET", "/status", nil))
+
+		if exp.closest != nil {
+			t.Errorf("Expected no closest match, got %s %s", exp.closest.req.Method, exp.closest.req.URL)
+		}
+	})
+
+	t.Run("Requests made outside an expectation's scope are not reported", func(t *testing.T) {
+		e := Expecter{}
+		e.LogReq(httptest.NewRequest("GET", "/users", nil))
+		exp := e.ExpectReq("POST", "/users")
+
+		if exp.closest != nil {
+			t.Errorf("Expected no closest match, got %s %s", exp.closest.req.Method, exp.closest.req.URL)
+		}
+	})
+
+	t.Run("Custom matchers are only called for requests with the right method and path", func(t *testing.T) {
+		e := Expecter{}
+		var calls []string
+		e.ExpectReq("POST", "/users").With(func(req *http.Request) bool {
+			calls = append(calls, req.Method+" "+req.URL.Path)
+			return true
+		})
+
+		e.LogReq(httptest.NewRequest("GET", "/users", nil))
+		e.LogReq(httptest.NewRequest("POST", "/status", nil))
+		e.LogReq(httptest.NewRequest("POST", "/users", nil))
+
+		if len(calls) != 1 || calls[0] != "POST /users" {
+			t.Errorf("Expected the matcher to be called only for POST /users, got %q", calls)
+		}
+	})
+
+	t.Run("Requests that matched another expectation are not reported", func(t *testing.T) {
+		e := Expecter{}
+		exp := e.ExpectReq("GET", "/users").WithQuery("page")
+		e.ExpectReq("GET", "/users")
+		e.LogReq(httptest.NewRequest("GET", "/users", nil))
+
+		if exp.closest != nil {
+			t.Errorf("Expected no closest match, got %s %s", exp.closest.req.Method, exp.closest.req.URL)
+		}
+	})
+}
+
+func TestMatcherResults(t *testing.T) {
+	testCases := []struct {
+		exp    func(e *Expecter) *Expectation
+		actual string
+	}{
+		{func(e *Expecter) *Expectation { return e.ExpectReq("GET", "/users").WithQuery("page", "2") }, `got page=["1"]`},
+		{func(e *Expecter) *Expectation { return e.ExpectReq("GET", "/users").WithQuery("q") }, `got no matching keys, found page`},
+		{func(e *Expecter) *Expectation { return e.ExpectReq("GET", "/users").WithHeader("X-Debug") }, `got none`},
+		{func(e *Expecter) *Expectation { return e.ExpectReq("GET", "/users").WithBody("name") }, `got none`},
+		{func(e *Expecter) *Expectation { return e.ExpectReq("GET", "/users").WithJSONBody(P{}) }, `request body is not valid JSON: EOF`},
+	}
+
+	for _, tc := range testCases {
+		e := Expecter{}
+		exp := tc.exp(&e)
+		e.LogReq(httptest.NewRequest("GET", "/users?page=1", nil))
+
+		if exp.closest == nil {
+			t.Errorf("%s: expected a closest match", exp.String())
+			continue
+		}
+		if got := exp.closest.checks[2].actual; got != tc.actual {
+			t.Errorf("%s: got %q, want %q", exp.String(), got, tc.actual)
+		}
+	}
+}
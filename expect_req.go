@@ -0,0 +1,123 @@
+package hex
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// parseExpectReqArgs converts the arguments given to ExpectReq into matchers for the method and path, and any further
+// matchers the request must satisfy
+func parseExpectReqArgs(args []interface{}) (method, path stringMatcher, matchers []matcher, err error) {
+	switch len(args) {
+	case 1:
+		if fn, ok := asMatchFn(args[0]); ok {
+			return mustMakeStringMatcher(Any), mustMakeStringMatcher(Any), []matcher{&withMatcher{fn: fn}}, nil
+		}
+		if params, ok := asParams(args[0]); ok {
+			return parseExpectReqParams(params)
+		}
+		if _, ok := args[0].(LogicalMatcher); ok {
+			m, err := makeRequestMatcher(args[0])
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			return mustMakeStringMatcher(Any), mustMakeStringMatcher(Any), []matcher{m}, nil
+		}
+		return nil, nil, nil, fmt.Errorf("a single argument must be a hex.MatchFn, hex.P or hex.LogicalMatcher, got %v", args[0])
+
+	case 2:
+		method, matchers, err = parseExpectReqArg("HTTP method", args[0], matchers)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		path, matchers, err = parseExpectReqArg("HTTP path", args[1], matchers)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return method, path, matchers, nil
+	}
+
+	return nil, nil, nil, fmt.Errorf("expected a method and path, or a single hex.MatchFn, hex.P or hex.LogicalMatcher, got %d arguments", len(args))
+}
+
+// parseExpectReqArg converts a method or path argument into a string matcher. A MatchFn accepts any method or path,
+// and is instead added to matchers.
+func parseExpectReqArg(name string, arg interface{}, matchers []matcher) (stringMatcher, []matcher, error) {
+	if fn, ok := asMatchFn(arg); ok {
+		return mustMakeStringMatcher(Any), append(matchers, &withMatcher{fn: fn}), nil
+	}
+
+	m, err := makeStringMatcher(arg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid %s matcher %v: %s", name, arg, err.Error())
+	}
+	return m, matchers, nil
+}
+
+// parseExpectReqParams converts a P with the keys method, path, query, header and body into matchers
+func parseExpectReqParams(params P) (method, path stringMatcher, matchers []matcher, err error) {
+	method, path = mustMakeStringMatcher(Any), mustMakeStringMatcher(Any)
+
+	// Add matchers in a fixed order, so the expectation's description doesn't depend on map iteration
+	for _, key := range []string{"method", "path", "query", "header", "body"} {
+		value, ok := params[key]
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "method":
+			method, matchers, err = parseExpectReqArg("HTTP method", value, matchers)
+		case "path":
+			path, matchers, err = parseExpectReqArg("HTTP path", value, matchers)
+		default:
+			var values urlValuesMatcher
+			args := []interface{}{value}
+			if values, err = makeURLValuesMatcher(args); err == nil {
+				switch key {
+				case "query":
+					matchers = append(matchers, &queryMatcher{args: args, urlValuesMatcher: values})
+				case "header":
+					values.forHeaders()
+					matchers = append(matchers, &headerMatcher{args: args, urlValuesMatcher: values})
+				case "body":
+					matchers = append(matchers, &bodyMatcher{args: args, urlValuesMatcher: values})
+				}
+			}
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: %s", key, err.Error())
+		}
+	}
+
+	for key := range params {
+		switch key {
+		case "method", "path", "query", "header", "body":
+		default:
+			return nil, nil, nil, fmt.Errorf("unknown key %v, expected one of method, path, query, header or body", key)
+		}
+	}
+
+	return method, path, matchers, nil
+}
+
+// asParams accepts a P, or the unnamed map type it's defined as
+func asParams(arg interface{}) (P, bool) {
+	switch params := arg.(type) {
+	case P:
+		return params, true
+	case map[interface{}]interface{}:
+		return params, true
+	}
+	return nil, false
+}
+
+func asMatchFn(arg interface{}) (MatchFn, bool) {
+	switch fn := arg.(type) {
+	case MatchFn:
+		return fn, true
+	case func(*http.Request) bool:
+		return fn, true
+	}
+	return nil, false
+}
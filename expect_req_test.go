@@ -0,0 +1,122 @@
+package hex
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func ExampleExpecter_ExpectReq_matchFn() {
+	e := Expecter{}
+
+	e.ExpectReq(MatchFn(func(req *http.Request) bool {
+		return req.Header.Get("X-Request-Id") != ""
+	}))
+
+	req := httptest.NewRequest("DELETE", "/users/123", nil)
+	req.Header.Set("X-Request-Id", "abc")
+	e.LogReq(req)
+
+	fmt.Println(e.Summary())
+	// Output:
+	// Expectations
+	// 	<any> <any> with <custom With matcher> - passed
+}
+
+func ExampleExpecter_ExpectReq_params() {
+	e := Expecter{}
+
+	e.ExpectReq(P{
+		"method": "GET",
+		"path":   Path("/users/{id}"),
+		"query":  P{"fields": "name"},
+	})
+
+	e.LogReq(httptest.NewRequest("GET", "/users/123?fields=name", nil))
+
+	fmt.Println(e.Summary())
+	// Output:
+	// Expectations
+	// 	GET /users/{id} with query string matching map[fields:name] - passed
+}
+
+func TestExpectReqArgs(t *testing.T) {
+	isPost := func(req *http.Request) bool { return req.Method == "POST" }
+
+	testCases := []struct {
+		name string
+		args []interface{}
+		req  *http.Request
+		want bool
+	}{
+		{"method and path", []interface{}{"GET", "/users"}, mockGet("/users"), true},
+		{"MatchFn", []interface{}{MatchFn(isPost)}, mockPost("/users", nil), true},
+		{"MatchFn not matching", []interface{}{MatchFn(isPost)}, mockGet("/users"), false},
+		{"unnamed func", []interface{}{isPost}, mockPost("/anything", nil), true},
+		{"MatchFn in place of method", []interface{}{MatchFn(isPost), "/users"}, mockPost("/users", nil), true},
+		{"MatchFn in place of method with wrong path", []interface{}{MatchFn(isPost), "/users"}, mockPost("/items", nil), false},
+		{"MatchFn in place of path", []interface{}{"GET", MatchFn(isPost)}, mockGet("/users"), false},
+		{"StringMatcher", []interface{}{StringMatcher(func(s string) bool { return s != "GET" }), "/users"}, mockPost("/users", nil), true},
+		{"empty P", []interface{}{P{}}, mockGet("/users"), true},
+		{"P with method", []interface{}{P{"method": "POST"}}, mockGet("/users"), false},
+		{"P with path", []interface{}{P{"path": R("^/users")}}, mockGet("/users/1"), true},
+		{"P with query", []interface{}{P{"query": "page"}}, mockGet("/users?page=1"), true},
+		{"P with missing query", []interface{}{P{"query": "page"}}, mockGet("/users"), false},
+		{"P with header", []interface{}{P{"header": P{"Accept": "text/plain"}}}, func() *http.Request {
+			req := mockGet("/")
+			req.Header.Set("Accept", "text/plain")
+			return req
+		}(), true},
+		{"P with body", []interface{}{P{"method": "POST", "body": P{"name": "bob"}}}, func() *http.Request {
+			req := mockPost("/users", strings.NewReader("name=bob"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			return req
+		}(), true},
+		{"P with MatchFn path", []interface{}{P{"method": "POST", "path": MatchFn(isPost)}}, mockPost("/x", nil), true},
+		{"map", []interface{}{map[interface{}]interface{}{"method": "GET"}}, mockGet("/users"), true},
+		{"map not matching", []interface{}{map[interface{}]interface{}{"method": "POST"}}, mockGet("/users"), false},
+		{"map with nested query map", []interface{}{map[interface{}]interface{}{
+			"query": map[interface{}]interface{}{"page": "1"},
+		}}, mockGet("/users?page=1"), true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := Expecter{}
+			e.ExpectReq(tc.args...)
+			e.LogReq(tc.req)
+			if got := e.Pass(); got != tc.want {
+				t.Errorf("Got %t, want %t\n%s", got, tc.want, e.Summary())
+			}
+		})
+	}
+}
+
+func TestExpectReqInvalidArgs(t *testing.T) {
+	testCases := []struct {
+		args []interface{}
+		want string
+	}{
+		{[]interface{}{}, "ExpectReq: expected a method and path, or a single hex.MatchFn, hex.P or hex.LogicalMatcher, got 0 arguments"},
+		{[]interface{}{"GET", "/", "x"}, "ExpectReq: expected a method and path, or a single hex.MatchFn, hex.P or hex.LogicalMatcher, got 3 arguments"},
+		{[]interface{}{"GET"}, "ExpectReq: a single argument must be a hex.MatchFn, hex.P or hex.LogicalMatcher, got GET"},
+		{[]interface{}{123, "/"}, "ExpectReq: Invalid HTTP method matcher 123: Cannot use value 123 when matching against strings"},
+		{[]interface{}{"GET", 123}, "ExpectReq: Invalid HTTP path matcher 123: Cannot use value 123 when matching against strings"},
+		{[]interface{}{P{"verb": "GET"}}, "ExpectReq: unknown key verb, expected one of method, path, query, header or body"},
+		{[]interface{}{P{"query": 123}}, "ExpectReq: query: Cannot use value 123 when matching against url.Values"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.want, func(t *testing.T) {
+			defer func() {
+				if got := fmt.Sprint(recover()); got != tc.want {
+					t.Errorf("Got panic %q, want %q", got, tc.want)
+				}
+			}()
+			e := Expecter{}
+			e.ExpectReq(tc.args...)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 )
 
 // Expectation captures details about an ExpectReq call and subsequent conditions
@@ -23,8 +24,33 @@ type Expectation struct {
 	matches  []*http.Request
 	matchers []matcher
 
-	handler     http.Handler
+	// handlers is the sequence of mock responses, see RespondWithHandler and Then
+	handlers    []http.Handler
+	then        bool
+	served      int
+	exhausted   ExhaustedBehavior
 	callThrough bool
+
+	// after holds expectations which must be matched before this one, see After and InOrder
+	after      []*Expectation
+	ordered    bool
+	violations []string
+
+	// closest is the unmatched request which came closest to matching, see diagnosis
+	closest *diagnosis
+
+	// strictScope is true while the scope opened by DoStrict is active
+	strictScope bool
+
+	// failures records problems detected while responding to requests, which cause the expectation to fail
+	failures []string
+
+	// delayMin and delayMax bound the delay before responding, and chunkDelay the delay between writes, see WithDelay
+	delayMin, delayMax time.Duration
+	chunkDelay         time.Duration
+
+	// cancelled records requests whose clients gave up while their response was delayed
+	cancelled []string
 }
 
 type quantifier struct {
@@ -32,9 +58,16 @@ type quantifier struct {
 	min, max, count uint
 }
 
+// String describes the expectation and whether it has passed or failed
 func (e *Expectation) String() string {
+	defer e.lock()()
+	return e.describe()
+}
+
+// describe is the implementation of String, for use when the Expecter's lock is already held
+func (e *Expectation) describe() string {
 	buf := &strings.Builder{}
-	fmt.Fprintf(buf, "%s %s", e.method.String(), e.path.String())
+	fmt.Fprintf(buf, e.name())
 	if len(e.matchers) > 0 {
 		fmt.Fprintf(buf, " with ")
 		for _, m := range e.matchers {
@@ -46,30 +79,66 @@ func (e *Expectation) String() string {
 		fmt.Fprintf(buf, " - passed")
 	} else {
 		fmt.Fprintf(buf, " - failed, %s", e.failureReason())
+	}
 
+	if len(e.cancelled) > 0 {
+		fmt.Fprintf(buf, " (client cancelled %s while delayed)", strings.Join(e.cancelled, ", "))
 	}
 
 	return buf.String()
 }
 
+// name returns the method and path of the expectation, without any further conditions
+func (e *Expectation) name() string {
+	return fmt.Sprintf("%s %s", e.method.String(), e.path.String())
+}
+
 func (e *Expectation) failureReason() string {
 	if e.pass() {
 		panic("failureReason called for non-failing expectation")
 	}
 
+	if len(e.violations) > 0 {
+		return fmt.Sprintf("%s (observed order: %s)", strings.Join(e.violations, "; "), e.expecter.observedOrder())
+	}
+
+	if len(e.failures) > 0 {
+		return strings.Join(e.failures, "; ")
+	}
+
 	if len(e.matches) == 0 {
 		return "no matching requests"
 	}
 
-	if e.quantifier != nil {
-		if e.quantifier.min == e.quantifier.max {
-			return fmt.Sprintf("expected %d matches, got %d", e.quantifier.min, e.quantifier.count)
+	if q := e.quantifier; q != nil {
+		switch {
+		case q.min == q.max:
+			return fmt.Sprintf("expected %d matches, got %d", q.min, q.count)
+		case q.max == Unbounded:
+			return fmt.Sprintf("expected at least %d matches, got %d", q.min, q.count)
+		case q.min == 0:
+			return fmt.Sprintf("expected at most %d matches, got %d", q.max, q.count)
 		}
-		return fmt.Sprintf("expected %d..%d matches, got %d", e.quantifier.min, e.quantifier.max, e.quantifier.count)
+		return fmt.Sprintf("expected %d..%d matches, got %d", q.min, q.max, q.count)
 	}
 	return ""
 }
 
+// lock acquires the lock of the Expecter that owns the expectation, and returns a function that releases it.
+// Every read or write of an expectation's state happens under this lock, so that expectations can be matched by
+// requests arriving on multiple goroutines.
+func (e *Expectation) lock() func() {
+	e.expecter.mu.Lock()
+	return e.expecter.mu.Unlock
+}
+
+// addMatcher adds a condition that requests must satisfy to match the expectation
+func (e *Expectation) addMatcher(m matcher) *Expectation {
+	defer e.lock()()
+	e.matchers = append(e.matchers, m)
+	return e
+}
+
 // Do opens a scope. Expectations in the current scope may be matched by requests in the current or nested scopes, but
 // requests in higher scopes cannot fulfill expections in lower scopes.
 //
@@ -90,6 +159,10 @@ func (e *Expectation) Do(fn func()) {
 }
 
 func (e *Expectation) pass() bool {
+	if len(e.failures) > 0 || len(e.violations) > 0 {
+		return false
+	}
+
 	if e.quantifier != nil {
 		return e.quantifier.count >= e.quantifier.min && e.quantifier.count <= e.quantifier.max
 	}
@@ -97,30 +170,21 @@ func (e *Expectation) pass() bool {
 	return len(e.matches) > 0
 }
 
-// Matches returns true if the expectation is fulfilled by the given http.Request
-func (e *Expectation) matchAgainst(req *http.Request) bool {
-	// Baseline check against method and path
-	if !e.method.match(req.Method) || !e.path.match(req.URL.Path) {
-		return false
-	}
-
-	for _, c := range e.matchers {
-		if !c.matches(req) {
-			return false
-		}
-	}
-
+// recordMatch records a request that matched the expectation. The Expecter's lock must be held.
+func (e *Expectation) recordMatch(req *http.Request) {
 	e.matches = append(e.matches, req)
 	if e.quantifier != nil {
 		e.quantifier.count++
 	}
-
-	return true
 }
 
 // Quantification
 
+// Unbounded can be given as the maximum to Between, to place no upper limit on the number of matches
+const Unbounded = ^uint(0)
+
 func (e *Expectation) quantify(desc string, min, max uint) {
+	defer e.lock()()
 	if e.quantifier != nil {
 		panic("A quantifier was added multiple times to the same expectations")
 	}
@@ -143,3 +207,32 @@ func (e *Expectation) Once() *Expectation {
 	e.quantify("once", 1, 1)
 	return e
 }
+
+// Times adds a quantity condition that requires exactly n requests to be matched
+func (e *Expectation) Times(n uint) *Expectation {
+	e.quantify("times", n, n)
+	return e
+}
+
+// AtLeast adds a quantity condition that requires n or more requests to be matched
+func (e *Expectation) AtLeast(n uint) *Expectation {
+	e.quantify("at least", n, Unbounded)
+	return e
+}
+
+// AtMost adds a quantity condition that allows no more than n requests to be matched.
+// An AtMost expectation passes if no requests are matched.
+func (e *Expectation) AtMost(n uint) *Expectation {
+	e.quantify("at most", 0, n)
+	return e
+}
+
+// Between adds a quantity condition that requires between min and max requests (inclusive) to be matched.
+// Use Unbounded as max to place no upper limit on the number of matches.
+func (e *Expectation) Between(min, max uint) *Expectation {
+	if min > max {
+		panic(fmt.Sprintf("Between called with min %d greater than max %d", min, max))
+	}
+	e.quantify("between", min, max)
+	return e
+}
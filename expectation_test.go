@@ -176,3 +176,98 @@ func TestOnce(t *testing.T) {
 		}
 	})
 }
+
+func ExampleExpectation_AtLeast() {
+	e := Expecter{}
+
+	e.ExpectReq("GET", "/status").AtLeast(3)
+
+	e.LogReq(httptest.NewRequest("GET", "/status", nil))
+	e.LogReq(httptest.NewRequest("GET", "/status", nil))
+
+	fmt.Println(e.Summary())
+	// Output:
+	// Expectations
+	// 	GET /status - failed, expected at least 3 matches, got 2
+}
+
+func ExampleExpectation_AtMost() {
+	e := Expecter{}
+
+	e.ExpectReq("GET", "/countries").AtMost(1)
+	e.ExpectReq("GET", "/cities").AtMost(1)
+
+	e.LogReq(httptest.NewRequest("GET", "/countries", nil))
+	e.LogReq(httptest.NewRequest("GET", "/countries", nil))
+
+	fmt.Println(e.Summary())
+	// Output:
+	// Expectations
+	// 	GET /cities - passed
+	// 	GET /countries - failed, expected at most 1 matches, got 2
+}
+
+func TestQuantifiers(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		quantify func(*Expectation) *Expectation
+		requests int
+		reason   string
+	}{
+		{"Times(2)", func(e *Expectation) *Expectation { return e.Times(2) }, 2, ""},
+		{"Times(2)", func(e *Expectation) *Expectation { return e.Times(2) }, 1, "expected 2 matches, got 1"},
+		{"Times(2)", func(e *Expectation) *Expectation { return e.Times(2) }, 3, "expected 2 matches, got 3"},
+		{"Times(0)", func(e *Expectation) *Expectation { return e.Times(0) }, 0, ""},
+
+		{"AtLeast(2)", func(e *Expectation) *Expectation { return e.AtLeast(2) }, 2, ""},
+		{"AtLeast(2)", func(e *Expectation) *Expectation { return e.AtLeast(2) }, 10, ""},
+		{"AtLeast(2)", func(e *Expectation) *Expectation { return e.AtLeast(2) }, 0, "no matching requests"},
+		{"AtLeast(2)", func(e *Expectation) *Expectation { return e.AtLeast(2) }, 1, "expected at least 2 matches, got 1"},
+		{"AtLeast(0)", func(e *Expectation) *Expectation { return e.AtLeast(0) }, 0, ""},
+
+		{"AtMost(2)", func(e *Expectation) *Expectation { return e.AtMost(2) }, 0, ""},
+		{"AtMost(2)", func(e *Expectation) *Expectation { return e.AtMost(2) }, 2, ""},
+		{"AtMost(2)", func(e *Expectation) *Expectation { return e.AtMost(2) }, 3, "expected at most 2 matches, got 3"},
+
+		{"Between(2, 3)", func(e *Expectation) *Expectation { return e.Between(2, 3) }, 2, ""},
+		{"Between(2, 3)", func(e *Expectation) *Expectation { return e.Between(2, 3) }, 3, ""},
+		{"Between(2, 3)", func(e *Expectation) *Expectation { return e.Between(2, 3) }, 1, "expected 2..3 matches, got 1"},
+		{"Between(2, 3)", func(e *Expectation) *Expectation { return e.Between(2, 3) }, 4, "expected 2..3 matches, got 4"},
+		{"Between(0, 1)", func(e *Expectation) *Expectation { return e.Between(0, 1) }, 0, ""},
+		{"Between(0, 1)", func(e *Expectation) *Expectation { return e.Between(0, 1) }, 2, "expected at most 1 matches, got 2"},
+		{"Between(1, Unbounded)", func(e *Expectation) *Expectation { return e.Between(1, Unbounded) }, 5, ""},
+		{"Between(2, Unbounded)", func(e *Expectation) *Expectation { return e.Between(2, Unbounded) }, 1, "expected at least 2 matches, got 1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s with %d requests", tc.desc, tc.requests), func(t *testing.T) {
+			e := Expecter{}
+			exp := tc.quantify(e.ExpectReq("GET", "/foo"))
+			for i := 0; i < tc.requests; i++ {
+				e.LogReq(httptest.NewRequest("GET", "/foo", nil))
+			}
+
+			if tc.reason == "" {
+				if !e.Pass() {
+					t.Errorf("Expected %s to pass, but it failed: %s", tc.desc, exp.String())
+				}
+			} else {
+				if e.Pass() {
+					t.Errorf("Expected %s to fail, but it passed", tc.desc)
+				} else if got := exp.failureReason(); got != tc.reason {
+					t.Errorf("failureReason: got %q, want %q", got, tc.reason)
+				}
+			}
+		})
+	}
+
+	t.Run("Between panics when min is greater than max", func(t *testing.T) {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Errorf("Expected Between(3, 2) to panic")
+			}
+		}()
+		e := Expecter{}
+		e.ExpectReq("GET", "/foo").Between(3, 2)
+	})
+}
@@ -5,21 +5,54 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"testing"
 )
 
-// Expecter is the top-level object onto which expectations are made
+// Expecter is the top-level object onto which expectations are made.
+//
+// An Expecter is safe for concurrent use: requests may be logged from multiple goroutines, such as the per-connection
+// goroutines of an httptest.Server, while expectations are being added or reported on. An Expecter must not be
+// copied after first use.
 type Expecter struct {
+	// mu guards the Expecter and every Expectation in its tree
+	mu sync.Mutex
+
 	root    *Expectation
 	current *Expectation
 
 	matched   []*http.Request
 	unmatched []*http.Request
+
+	// ordered holds the requests which matched expectations used with After or InOrder
+	ordered []*http.Request
+
+	// strictUnmatched holds unmatched requests that were logged in strict mode, which cause the Expecter to fail
+	strictUnmatched []*http.Request
+	strict          bool
+	unmatchedStatus int
+
+	// unreadable holds the errors of unmatched requests whose bodies could not be read
+	unreadable map[*http.Request]error
+
+	maxBodySize int64
+
+	// hung holds connections held open by RespondWithHang or ExpectWebSocket, hungDone is signalled when one is
+	// forgotten, and hungClosed is set once they have been closed, see closeHungConnections
+	hung       []*hungConn
+	hungDone   *sync.Cond
+	hungClosed bool
 }
 
 // Pass returns true if all expectations have passed
 func (e *Expecter) Pass() bool {
-	return len(e.FailedExpectations()) == 0
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.pass()
+}
+
+func (e *Expecter) pass() bool {
+	return len(e.failedExpectations()) == 0 && len(e.strictUnmatched) == 0
 }
 
 // Fail returns true if any expectation has failed
@@ -29,11 +62,19 @@ func (e *Expecter) Fail() bool {
 
 // UnmatchedRequests returns a list of all http.Request objects that didn't match any expectation
 func (e *Expecter) UnmatchedRequests() []*http.Request {
-	return e.unmatched
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*http.Request(nil), e.unmatched...)
 }
 
 // PassedExpectations returns all passing expectations
-func (e *Expecter) PassedExpectations() (passed []*Expectation) {
+func (e *Expecter) PassedExpectations() []*Expectation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.passedExpectations()
+}
+
+func (e *Expecter) passedExpectations() (passed []*Expectation) {
 	if e.root == nil {
 		return
 	}
@@ -52,7 +93,13 @@ func (e *Expecter) PassedExpectations() (passed []*Expectation) {
 }
 
 // FailedExpectations returns a list of currently failing
-func (e *Expecter) FailedExpectations() (failed []*Expectation) {
+func (e *Expecter) FailedExpectations() []*Expectation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.failedExpectations()
+}
+
+func (e *Expecter) failedExpectations() (failed []*Expectation) {
 	if e.root == nil {
 		return
 	}
@@ -70,8 +117,33 @@ func (e *Expecter) FailedExpectations() (failed []*Expectation) {
 	return
 }
 
-// ExpectReq adds an Expectation to the stack
-func (e *Expecter) ExpectReq(method, path interface{}) (exp *Expectation) {
+// ExpectReq adds an Expectation to the stack. It accepts either an HTTP method and path:
+//
+//   ExpectReq("GET", "/users")
+//   ExpectReq(hex.R("^(POST|PATCH)$"), hex.Path("/users/{id}"))
+//
+// Or a single MatchFn, which is given the whole request:
+//
+//   ExpectReq(hex.MatchFn(func(req *http.Request) bool { return req.ContentLength > 0 }))
+//
+// Or a single LogicalMatcher combining MatchFns and Ps, see Matching.
+//
+// Or a single P, with any of the keys "method", "path", "query", "header" and "body", whose values are given to
+// ExpectReq, WithQuery, WithHeader and WithBody respectively:
+//
+//   ExpectReq(hex.P{"method": "GET", "path": "/search", "query": hex.P{"q": "test"}})
+//
+// A MatchFn may also be given in place of the method or path, in which case any method or path is accepted and the
+// function must return true for the request to match.
+func (e *Expecter) ExpectReq(args ...interface{}) (exp *Expectation) {
+	methodMatcher, pathMatcher, matchers, err := parseExpectReqArgs(args)
+	if err != nil {
+		log.Panicf("ExpectReq: %s", err.Error())
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
 	if e.root == nil {
 		// Lazily initialize the Expecter, so the zero-value is usable
 		e.root = &Expectation{
@@ -82,19 +154,10 @@ func (e *Expecter) ExpectReq(method, path interface{}) (exp *Expectation) {
 		e.current = e.root
 	}
 
-	methodMatcher, err := makeStringMatcher(method)
-	if err != nil {
-		log.Panicf("Invalid HTTP method matcher %v in ExpectReq: %s", method, err.Error())
-	}
-
-	pathMatcher, err := makeStringMatcher(path)
-	if err != nil {
-		log.Panicf("Invalid HTTP path matcher %v in ExpectReq: %s", path, err.Error())
-	}
-
 	exp = &Expectation{
 		method:   methodMatcher,
 		path:     pathMatcher,
+		matchers: matchers,
 		expecter: e,
 		parent:   e.current,
 	}
@@ -105,40 +168,119 @@ func (e *Expecter) ExpectReq(method, path interface{}) (exp *Expectation) {
 	return
 }
 
-// LogReq matches an incoming request against he current tree of Expectations, and returns the matched Expectation if any
+// LogReq matches an incoming request against he current tree of Expectations, and returns the matched Expectation if any.
+//
+// The request's body is buffered (see SetMaxBodySize) so that every matcher can read it, and is rewound before
+// LogReq returns so that it can be read again by any handler. If the body cannot be read, for example because the
+// client aborted an upload, the request isn't matched against any expectation and is reported as unmatched.
 func (e *Expecter) LogReq(req *http.Request) *Expectation {
-	// Ascend up the stack, looking for expectations that match the given request
-	var matched *Expectation
+	exp, _, _ := e.logReq(req)
+	return exp
+}
+
+// logReq is LogReq, but also returns the mock response chosen for the request if it matched, and the error if the
+// request's body could not be read
+func (e *Expecter) logReq(req *http.Request) (*Expectation, response, error) {
+	e.mu.Lock()
+	limit := e.bodyLimit()
+	e.mu.Unlock()
+
+	// Read the body before taking the lock, so that slow clients don't block other requests
+	body, err := captureBody(req, limit)
+	defer body.restore(req)
+
+	e.mu.Lock()
+	strict := e.inStrictScope()
+	if err != nil {
+		defer e.mu.Unlock()
+		if e.unreadable == nil {
+			e.unreadable = map[*http.Request]error{}
+		}
+		e.unreadable[req] = err
+		e.logUnmatched(req, strict)
+		return nil, response{}, err
+	}
+
+	// Ascend up the stack, collecting the expectations the request may match along with their current matchers
+	var candidates []*Expectation
+	var matchers [][]matcher
 	for exp := e.current; exp != e.root; exp = exp.parent {
-		if exp.matchAgainst(req) {
-			matched = exp
+		candidates = append(candidates, exp)
+		matchers = append(matchers, exp.matchers)
+	}
+	e.mu.Unlock()
+
+	// Matchers run without the lock held, so that a MatchFn can inspect the Expecter without deadlocking
+	diagnoses := make([]*diagnosis, len(candidates))
+	for i, exp := range candidates {
+		diagnoses[i] = exp.evaluate(req, matchers[i])
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var matched *Expectation
+	var hits []*Expectation
+	var misses []*diagnosis
+	for _, d := range diagnoses {
+		if d.pass() {
+			d.exp.recordMatch(req)
+			matched = d.exp
+			hits = append(hits, d.exp)
+		} else {
+			misses = append(misses, d)
+		}
+	}
+
+	// Ordering is checked once every expectation has recorded the request, so that a request matching both an
+	// expectation and one of its prerequisites satisfies the prerequisite rather than violating it
+	for _, exp := range hits {
+		if exp.ordered {
+			exp.checkOrder(req)
 		}
 	}
 
 	// When we reach the top level, we want to capture unmatched HTTP requests, so we can
 	// display them in a report.
-	if matched != nil {
-		e.matched = append(e.matched, req)
-	} else {
-		e.unmatched = append(e.unmatched, req)
+	if matched == nil {
+		e.logUnmatched(req, strict)
+		for _, d := range misses {
+			d.exp.considerClosest(d)
+		}
+		return nil, response{}, nil
 	}
 
-	return matched
+	e.matched = append(e.matched, req)
+	return matched, matched.nextResponse(), nil
+}
+
+// logUnmatched records a request that didn't match any expectation, and whether it was logged in strict mode. The
+// Expecter's lock must be held.
+func (e *Expecter) logUnmatched(req *http.Request, strict bool) {
+	e.unmatched = append(e.unmatched, req)
+	if strict {
+		e.strictUnmatched = append(e.strictUnmatched, req)
+	}
 }
 
 // do introduces a nested scope.
 // When `do` is finished, the stack unwinds back to the expectation that introduced
 // the scope, removing all nested expectation scopes.
 func (e *Expecter) do(fn func()) {
+	e.mu.Lock()
 	if e.current == nil {
+		e.mu.Unlock()
 		panic("Somehow `do` was invoked with an empty expectation stack")
 	}
-
 	current := e.current
+	e.mu.Unlock()
 
+	// fn will typically add expectations and log requests, so it must run without the lock held
 	fn()
 
+	e.mu.Lock()
 	e.current = current.parent
+	e.mu.Unlock()
 }
 
 // TestingT covers the minimal interface we consume from a testing.T
@@ -170,34 +312,51 @@ func (t *captureT) Cleanup(func()) {}
 
 // Summary returns a summary of all passed/failed expectations and any requests that didn't match
 func (e *Expecter) Summary() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
 	t := captureT{}
 	e.writeSummary(&t)
 	return t.buf.String()
 }
 
+// writeSummary writes the summary to t. The Expecter's lock must be held.
 func (e *Expecter) writeSummary(t TestingT) {
 	t.Helper()
 	t.Logf("Expectations\n")
-	for _, exp := range e.PassedExpectations() {
-		t.Logf("\t%s\n", exp.String())
+	for _, exp := range e.passedExpectations() {
+		t.Logf("\t%s\n", exp.describe())
 	}
-	for _, exp := range e.FailedExpectations() {
-		t.Logf("\t%s\n", exp.String())
+	for _, exp := range e.failedExpectations() {
+		t.Logf("\t%s\n", exp.describe())
+		if len(exp.matches) == 0 && exp.closest != nil {
+			exp.closest.write(t)
+		}
 	}
 
-	if len(e.UnmatchedRequests()) > 0 {
+	if len(e.unmatched) > 0 {
 		t.Logf("Unmatched Requests\n")
-		for _, req := range e.UnmatchedRequests() {
-			t.Logf("\t%s %s\n", req.Method, req.URL.Path)
+		for _, req := range e.unmatched {
+			line := fmt.Sprintf("%s %s", req.Method, req.URL.Path)
+			if err, ok := e.unreadable[req]; ok {
+				line += fmt.Sprintf(" - cannot read body: %s", err.Error())
+			}
+			if e.isStrictUnmatched(req) {
+				line += " - failed, unexpected request in strict mode"
+			}
+			t.Logf("\t%s\n", line)
 		}
 	}
 }
 
 // HexReport logs a summary of passes/fails to the given testing object, and calls t.Errorf with an error message if
-// any expectations failed
+// any expectations failed, or if any requests went unmatched in strict mode
 func (e *Expecter) HexReport(t TestingT) {
 	t.Helper()
-	if e.Fail() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.pass() {
 		t.Errorf("One or more HTTP expectations failed\n")
 	}
 
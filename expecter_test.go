@@ -33,14 +33,14 @@ func TestExpecter(t *testing.T) {
 
 	t.Run("With no expectations and no nesting, the Expecter passes", func(t *testing.T) {
 		e := Expecter{}
-		assertPassedFailed(t, 0, 0, e)
+		assertPassedFailed(t, 0, 0, &e)
 	})
 
 	t.Run("requests logged before expectations do not match", func(t *testing.T) {
 		e := Expecter{}
 		e.LogReq(mockGet("/foo"))
 		e.ExpectReq("GET", "/foo")
-		assertPassedFailed(t, 0, 1, e)
+		assertPassedFailed(t, 0, 1, &e)
 	})
 
 	t.Run("Expectations made at the top-level scope are tested by Done", func(t *testing.T) {
@@ -48,7 +48,7 @@ func TestExpecter(t *testing.T) {
 			e := Expecter{}
 			e.ExpectReq("GET", "/foobar")
 			e.LogReq(mockGet("/foobar"))
-			assertPassedFailed(t, 1, 0, e)
+			assertPassedFailed(t, 1, 0, &e)
 		})
 
 		t.Run("With many passing expectations", func(t *testing.T) {
@@ -57,13 +57,13 @@ func TestExpecter(t *testing.T) {
 			e.ExpectReq("POST", "/foobar2")
 			e.LogReq(mockGet("/foobar"))
 			e.LogReq(mockPost("/foobar2", nil))
-			assertPassedFailed(t, 2, 0, e)
+			assertPassedFailed(t, 2, 0, &e)
 		})
 
 		t.Run("With one failing expectation", func(t *testing.T) {
 			e := Expecter{}
 			e.ExpectReq("GET", "/foobar")
-			assertPassedFailed(t, 0, 1, e)
+			assertPassedFailed(t, 0, 1, &e)
 		})
 
 		t.Run("With one failing and one passing expectation", func(t *testing.T) {
@@ -71,7 +71,7 @@ func TestExpecter(t *testing.T) {
 			e.ExpectReq("GET", "/foobar")
 			e.ExpectReq("POST", "/foobar2")
 			e.LogReq(mockPost("/foobar2", nil))
-			assertPassedFailed(t, 1, 1, e)
+			assertPassedFailed(t, 1, 1, &e)
 		})
 	})
 
@@ -83,7 +83,7 @@ func TestExpecter(t *testing.T) {
 				e.LogReq(httptest.NewRequest("GET", "/foobar", nil))
 			})
 
-			assertPassed(t, 1, e)
+			assertPassed(t, 1, &e)
 		})
 
 		t.Run("request out of scope", func(t *testing.T) {
@@ -93,7 +93,7 @@ func TestExpecter(t *testing.T) {
 			e.ExpectReq("GET", "/foobar").Do(func() {
 			})
 
-			assertFailed(t, 1, e)
+			assertFailed(t, 1, &e)
 		})
 	})
 
@@ -103,8 +103,8 @@ func TestExpecter(t *testing.T) {
 		e.ExpectReq("GET", "/foobar").Do(func() {
 			e.LogReq(mockGet("/xyz"))
 		})
-		assertFailed(t, 1, e)
-		assertUnused(t, e, mockGet("/xyz"), mockGet("/abc"))
+		assertFailed(t, 1, &e)
+		assertUnused(t, &e, mockGet("/xyz"), mockGet("/abc"))
 
 	})
 
@@ -118,7 +118,7 @@ func TestExpecter(t *testing.T) {
 			})
 		})
 
-		assertPassed(t, 2, e)
+		assertPassed(t, 2, &e)
 	})
 
 	t.Run("When complex nested expectations are met, the result is a pass", func(t *testing.T) {
@@ -138,7 +138,7 @@ func TestExpecter(t *testing.T) {
 			})
 		})
 
-		assertPassed(t, 4, e)
+		assertPassed(t, 4, &e)
 	})
 
 	t.Run("When an inner expectation would be matched by a request logged in an outer expectation, there is no match", func(t *testing.T) {
@@ -153,7 +153,7 @@ func TestExpecter(t *testing.T) {
 			})
 		})
 
-		assertPassedFailed(t, 1, 1, e)
+		assertPassedFailed(t, 1, 1, &e)
 	})
 
 	t.Run("When one requests matches multiple expectations, a expectation are met", func(t *testing.T) {
@@ -164,7 +164,7 @@ func TestExpecter(t *testing.T) {
 			})
 		})
 
-		assertPassedFailed(t, 2, 0, e)
+		assertPassedFailed(t, 2, 0, &e)
 	})
 
 	t.Run("When simply nested expectations are not met, the result is a fail", func(t *testing.T) {
@@ -176,7 +176,7 @@ func TestExpecter(t *testing.T) {
 			})
 		})
 
-		assertPassedFailed(t, 1, 1, e)
+		assertPassedFailed(t, 1, 1, &e)
 	})
 
 	t.Run("When complex nested expectations are not met, the result is a fail", func(t *testing.T) {
@@ -190,7 +190,7 @@ func TestExpecter(t *testing.T) {
 			})
 		})
 
-		assertFailed(t, 4, e)
+		assertFailed(t, 4, &e)
 	})
 
 	t.Run("When complex nested expectations are partially met, the result is a fail", func(t *testing.T) {
@@ -208,8 +208,8 @@ func TestExpecter(t *testing.T) {
 			})
 		})
 
-		assertPassedFailed(t, 1, 3, e)
-		assertUnused(t, e, mockPost("/foobar2", nil))
+		assertPassedFailed(t, 1, 3, &e)
+		assertUnused(t, &e, mockPost("/foobar2", nil))
 	})
 }
 
@@ -253,7 +253,7 @@ func mockPost(path string, body io.Reader) *http.Request {
 	return httptest.NewRequest("POST", path, body)
 }
 
-func assertUnused(t *testing.T, e Expecter, requests ...*http.Request) {
+func assertUnused(t *testing.T, e *Expecter, requests ...*http.Request) {
 	fail := len(e.UnmatchedRequests()) != len(requests)
 
 	if !fail {
@@ -288,7 +288,7 @@ func assertUnused(t *testing.T, e Expecter, requests ...*http.Request) {
 	}
 }
 
-func assertPassedFailed(t *testing.T, wantNumPassed, wantNumFailed int, e Expecter) {
+func assertPassedFailed(t *testing.T, wantNumPassed, wantNumFailed int, e *Expecter) {
 	t.Helper()
 
 	t.Log(e.Summary())
@@ -326,12 +326,12 @@ func assertPassedFailed(t *testing.T, wantNumPassed, wantNumFailed int, e Expect
 	}
 }
 
-func assertPassed(t *testing.T, numPassed int, e Expecter) {
+func assertPassed(t *testing.T, numPassed int, e *Expecter) {
 	t.Helper()
 	assertPassedFailed(t, numPassed, 0, e)
 }
 
-func assertFailed(t *testing.T, numFailed int, e Expecter) {
+func assertFailed(t *testing.T, numFailed int, e *Expecter) {
 	t.Helper()
 	assertPassedFailed(t, 0, numFailed, e)
 }
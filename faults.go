@@ -0,0 +1,177 @@
+package hex
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Fault responses misbehave at the connection level, to test how clients handle broken servers. They take over the
+// connection using http.Hijacker, which is supported by the servers returned by NewServer and NewTLSServer. When the
+// ResponseWriter can't be hijacked, for example when the response is also delayed by WithChunkDelay, they abort the
+// request by panicking with http.ErrAbortHandler, which closes the connection without a response.
+
+// RespondWithConnectionReset closes the connection without writing a response. Where possible the connection is reset
+// rather than closed gracefully, so the client sees "connection reset by peer".
+func (e *Expectation) RespondWithConnectionReset() *Expectation {
+	return e.RespondWithFn(func(rw http.ResponseWriter, req *http.Request) {
+		conn := hijack(rw)
+		// A linger of 0 discards unsent data and sends RST instead of FIN
+		if tcp, ok := conn.(interface{ SetLinger(int) error }); ok {
+			tcp.SetLinger(0)
+		}
+		conn.Close()
+	})
+}
+
+// RespondWithTruncatedBody responds with the given status, and a Content-Length for the whole of body, but closes the
+// connection after writing only the first n bytes of body. Clients typically report an unexpected EOF.
+func (e *Expectation) RespondWithTruncatedBody(status int, body string, n int) *Expectation {
+	if n < 0 || n >= len(body) {
+		panic(fmt.Sprintf("RespondWithTruncatedBody: n must be between 0 and %d, got %d", len(body)-1, n))
+	}
+	return e.RespondWithFn(func(rw http.ResponseWriter, req *http.Request) {
+		conn := hijack(rw)
+		defer conn.Close()
+		writeRawResponse(conn, status, rw.Header(), len(body), body[:n])
+	})
+}
+
+// RespondWithWrongContentLength responds with the given status and body, but with a Content-Length of contentLength.
+// A Content-Length longer than body leaves the client waiting for data that never arrives until the connection is
+// closed, and a shorter one leaves extra bytes on the connection, which corrupt the next response.
+func (e *Expectation) RespondWithWrongContentLength(status int, body string, contentLength int) *Expectation {
+	if contentLength < 0 {
+		panic(fmt.Sprintf("RespondWithWrongContentLength: invalid Content-Length %d", contentLength))
+	}
+	return e.RespondWithFn(func(rw http.ResponseWriter, req *http.Request) {
+		conn := hijack(rw)
+		defer conn.Close()
+		writeRawResponse(conn, status, rw.Header(), contentLength, body)
+	})
+}
+
+// RespondWithMalformedHeaders responds with a status line followed by a header line that isn't valid HTTP, and closes
+// the connection. Clients typically report a malformed header.
+func (e *Expectation) RespondWithMalformedHeaders() *Expectation {
+	return e.RespondWithFn(func(rw http.ResponseWriter, req *http.Request) {
+		conn := hijack(rw)
+		defer conn.Close()
+		io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Type text/plain\r\nContent-Length: 2\r\n\r\nok")
+	})
+}
+
+// RespondWithHang accepts the request but never responds, leaving the client waiting until it gives up. Hung
+// connections are closed when the client closes them, or when the Server is closed or its test ends.
+func (e *Expectation) RespondWithHang() *Expectation {
+	return e.RespondWithFn(func(rw http.ResponseWriter, req *http.Request) {
+		attach, forget := e.expecter.trackHung()
+		defer forget()
+		conn := hijack(rw)
+		attach(conn)
+		// Reading returns once either side closes the connection
+		io.Copy(io.Discard, conn)
+		conn.Close()
+	})
+}
+
+// hijack takes over the connection of a response, or aborts the request if the connection can't be taken over
+func hijack(rw http.ResponseWriter) net.Conn {
+	conn, _ := hijackBuffered(rw)
+	return conn
+}
+
+// hijackBuffered is like hijack, but also returns a reader holding any data the client has already sent
+func hijackBuffered(rw http.ResponseWriter) (net.Conn, *bufio.Reader) {
+	hijacker, ok := rw.(http.Hijacker)
+	if !ok {
+		panic(http.ErrAbortHandler)
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	return conn, buf.Reader
+}
+
+// writeRawResponse writes an HTTP/1.1 response directly to a hijacked connection, with the given Content-Length
+// regardless of the length of body
+func writeRawResponse(conn net.Conn, status int, header http.Header, contentLength int, body string) {
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "HTTP/1.1 %d %s\r\n", status, http.StatusText(status))
+	header = header.Clone()
+	header.Set("Content-Length", fmt.Sprint(contentLength))
+	header.Set("Connection", "close")
+	header.Write(buf)
+	buf.WriteString("\r\n")
+	buf.WriteString(body)
+	io.WriteString(conn, buf.String())
+}
+
+// hungConn is a connection held open by RespondWithHang or ExpectWebSocket. Its conn is nil until the connection has
+// been hijacked.
+type hungConn struct {
+	conn net.Conn
+}
+
+// trackHung registers a connection that's about to be held open by RespondWithHang or ExpectWebSocket, so that
+// closeHungConnections closes it and waits for its handler. It must be called before the connection is hijacked, so
+// that handlers which are still hijacking are waited for too. The returned attach function must be given the hijacked
+// connection, and forget must be called when the handler returns.
+func (e *Expecter) trackHung() (attach func(net.Conn), forget func()) {
+	h := &hungConn{}
+	e.mu.Lock()
+	e.hung = append(e.hung, h)
+	e.mu.Unlock()
+
+	attach = func(conn net.Conn) {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		h.conn = conn
+		if e.hungClosed {
+			conn.Close()
+		}
+	}
+
+	forget = func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		for i, c := range e.hung {
+			if c == h {
+				e.hung = append(e.hung[:i], e.hung[i+1:]...)
+				break
+			}
+		}
+		e.hungCond().Broadcast()
+	}
+	return attach, forget
+}
+
+// closeHungConnections closes every connection held open by RespondWithHang or ExpectWebSocket, and waits for their
+// handlers to return, so that any failures they record are included in a report made afterwards. Connections
+// hijacked after it has been called are closed immediately.
+func (e *Expecter) closeHungConnections() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.hungClosed = true
+	for _, h := range e.hung {
+		if h.conn != nil {
+			h.conn.Close()
+		}
+	}
+	for len(e.hung) > 0 {
+		e.hungCond().Wait()
+	}
+}
+
+// hungCond returns the condition signalled when a hung connection is forgotten. The Expecter's lock must be held.
+func (e *Expecter) hungCond() *sync.Cond {
+	if e.hungDone == nil {
+		e.hungDone = sync.NewCond(&e.mu)
+	}
+	return e.hungDone
+}
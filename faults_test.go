@@ -0,0 +1,147 @@
+package hex
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFaultResponses(t *testing.T) {
+	get := func(s *Server, path string) (string, error) {
+		client := &http.Client{Transport: &http.Transport{}, Timeout: 5 * time.Second}
+		resp, err := client.Get(s.URL + path)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	t.Run("RespondWithConnectionReset", func(t *testing.T) {
+		s := NewServer(t, nil)
+		s.ExpectReq("GET", "/reset").RespondWithConnectionReset()
+
+		if _, err := get(s, "/reset"); err == nil {
+			t.Errorf("Expected a reset connection to fail the request")
+		}
+	})
+
+	t.Run("RespondWithTruncatedBody", func(t *testing.T) {
+		s := NewServer(t, nil)
+		s.ExpectReq("GET", "/truncated").RespondWithTruncatedBody(200, "hello world", 5)
+
+		body, err := get(s, "/truncated")
+		if err != io.ErrUnexpectedEOF {
+			t.Errorf("Expected unexpected EOF, got %v", err)
+		}
+		if body != "hello" {
+			t.Errorf("Body: got %q, want %q", body, "hello")
+		}
+	})
+
+	t.Run("RespondWithWrongContentLength", func(t *testing.T) {
+		testCases := []struct {
+			name          string
+			contentLength int
+			body          string
+			err           error
+		}{
+			{"longer than the body", 20, "hello", io.ErrUnexpectedEOF},
+			{"shorter than the body", 2, "he", nil},
+		}
+
+		for _, tc := range testCases {
+			tc := tc
+			t.Run(tc.name, func(t *testing.T) {
+				s := NewServer(t, nil)
+				s.ExpectReq("GET", "/length").RespondWithWrongContentLength(200, "hello", tc.contentLength)
+
+				body, err := get(s, "/length")
+				if err != tc.err {
+					t.Errorf("Error: got %v, want %v", err, tc.err)
+				}
+				if body != tc.body {
+					t.Errorf("Body: got %q, want %q", body, tc.body)
+				}
+			})
+		}
+	})
+
+	t.Run("RespondWithMalformedHeaders", func(t *testing.T) {
+		s := NewServer(t, nil)
+		s.ExpectReq("GET", "/malformed").RespondWithMalformedHeaders()
+
+		if _, err := get(s, "/malformed"); err == nil || !strings.Contains(err.Error(), "malformed") {
+			t.Errorf("Expected a malformed header error, got %v", err)
+		}
+	})
+
+	t.Run("RespondWithHang", func(t *testing.T) {
+		s := NewServer(t, nil)
+		s.ExpectReq("GET", "/hang").RespondWithHang()
+
+		client := &http.Client{Transport: &http.Transport{}, Timeout: 50 * time.Millisecond}
+		if resp, err := client.Get(s.URL + "/hang"); err == nil {
+			resp.Body.Close()
+			t.Fatalf("Expected the request to time out")
+		}
+
+		s.Close()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if len(s.hung) != 0 {
+			t.Errorf("Expected Close to close hung connections, %d remain", len(s.hung))
+		}
+	})
+
+	t.Run("RespondWithHang forgets connections closed by the client", func(t *testing.T) {
+		s := NewServer(t, nil)
+		s.ExpectReq("GET", "/hang").RespondWithHang()
+
+		client := &http.Client{Transport: &http.Transport{}, Timeout: 50 * time.Millisecond}
+		if resp, err := client.Get(s.URL + "/hang"); err == nil {
+			resp.Body.Close()
+			t.Fatalf("Expected the request to time out")
+		}
+		client.Transport.(*http.Transport).CloseIdleConnections()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			s.mu.Lock()
+			remaining := len(s.hung)
+			s.mu.Unlock()
+			if remaining == 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected closed connections to be forgotten, %d remain", remaining)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+
+	t.Run("Aborts when the connection cannot be hijacked", func(t *testing.T) {
+		s := &Server{}
+		s.ExpectReq("GET", "/reset").RespondWithConnectionReset()
+
+		defer func() {
+			if r := recover(); r != http.ErrAbortHandler {
+				t.Errorf("Expected panic with http.ErrAbortHandler, got %v", r)
+			}
+		}()
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/reset", nil))
+	})
+
+	t.Run("Invalid truncation panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil || !strings.HasPrefix(r.(string), "RespondWithTruncatedBody: ") {
+				t.Errorf("Expected RespondWithTruncatedBody to panic, got %v", r)
+			}
+		}()
+		(&Server{}).ExpectReq("GET", "/").RespondWithTruncatedBody(200, "abc", 3)
+	})
+}
@@ -0,0 +1,108 @@
+package hex
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RespondWithFile responds with the given status and the contents of the file at path, which is read when each request
+// is served. The Content-Type is guessed from the file's extension. If the file cannot be read, the expectation fails
+// and the request receives a 500 response.
+//
+//   server.ExpectReq("GET", "/users").RespondWithFile(200, "testdata/users.json")
+func (e *Expectation) RespondWithFile(status int, path string) *Expectation {
+	return e.RespondWithFn(func(rw http.ResponseWriter, req *http.Request) {
+		e.serveFixture(rw, status, path)
+	})
+}
+
+// RespondFromDir responds to each request with a fixture file from dir, chosen by the request's method and path. A
+// request for GET /users/123 is served from dir/GET/users/123 with any extension, ie dir/GET/users/123.json, and a
+// request for a path ending in a slash is served from an index file, ie dir/GET/users/index.json. The Content-Type is
+// guessed from the file's extension.
+//
+//   server.ExpectReq(hex.Any, hex.Any).RespondFromDir("testdata/api")
+//
+// Fixtures are served with a 200 status. If no fixture exists for a request, the expectation fails, naming the file
+// that was expected, and the request receives a 500 response.
+func (e *Expectation) RespondFromDir(dir string) *Expectation {
+	return e.RespondWithFn(func(rw http.ResponseWriter, req *http.Request) {
+		fixture, err := findFixture(dir, req.Method, req.URL.Path)
+		if err != nil {
+			e.recordFailure("%s", err.Error())
+			http.Error(rw, "hex: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		e.serveFixture(rw, http.StatusOK, fixture)
+	})
+}
+
+// serveFixture copies the file at path to the response
+func (e *Expectation) serveFixture(rw http.ResponseWriter, status int, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		e.recordFailure("cannot read fixture: %s", err.Error())
+		http.Error(rw, "hex: cannot read fixture: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	rw.Header().Set("Content-Type", contentType)
+	rw.WriteHeader(status)
+
+	// Errors here are most likely due to the client disconnecting, which isn't a problem with the fixture
+	io.Copy(rw, f)
+}
+
+// findFixture finds the fixture file for a request by convention, see RespondFromDir
+func findFixture(dir, method, urlPath string) (string, error) {
+	// The method names a directory, so methods such as ".." mustn't be able to name any other
+	if method == "." || method == ".." || filepath.Base(method) != method {
+		return "", fmt.Errorf("no fixture for %s %s, invalid method %q", method, urlPath, method)
+	}
+
+	// Cleaning the rooted path prevents requests from escaping dir with ".."
+	clean := path.Clean("/" + urlPath)
+	if strings.HasSuffix(urlPath, "/") {
+		clean = path.Join(clean, "index")
+	}
+	base := filepath.Join(dir, method, filepath.FromSlash(clean))
+
+	if info, err := os.Stat(base); err == nil && info.Mode().IsRegular() {
+		return base, nil
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(base))
+	if err == nil {
+		var candidates []string
+		prefix := filepath.Base(base) + "."
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
+				candidates = append(candidates, entry.Name())
+			}
+		}
+		if len(candidates) > 0 {
+			sort.Strings(candidates)
+			return filepath.Join(filepath.Dir(base), candidates[0]), nil
+		}
+	}
+
+	return "", fmt.Errorf("no fixture for %s %s, expected %s.*", method, urlPath, base)
+}
+
+// recordFailure fails the expectation with the given reason, for problems detected while responding to a request
+func (e *Expectation) recordFailure(format string, args ...interface{}) {
+	defer e.lock()()
+	e.failures = append(e.failures, fmt.Sprintf(format, args...))
+}
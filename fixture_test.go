@@ -0,0 +1,154 @@
+package hex
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFixture(t *testing.T, dir, name, contents string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func serve(s *Server, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func TestRespondWithFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "users.json", `[{"id": 1}]`)
+	writeFixture(t, dir, "blob", `data`)
+
+	testCases := []struct {
+		file        string
+		contentType string
+		body        string
+	}{
+		{"users.json", "application/json", `[{"id": 1}]`},
+		{"blob", "application/octet-stream", `data`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.file, func(t *testing.T) {
+			s := NewServer(t, nil)
+			s.ExpectReq("GET", "/fixture").RespondWithFile(201, filepath.Join(dir, tc.file))
+
+			rec := serve(s, "GET", "/fixture")
+			if rec.Code != 201 {
+				t.Errorf("Status: got %d, want 201", rec.Code)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tc.contentType {
+				t.Errorf("Content-Type: got %q, want %q", got, tc.contentType)
+			}
+			if got := rec.Body.String(); got != tc.body {
+				t.Errorf("Body: got %q, want %q", got, tc.body)
+			}
+		})
+	}
+
+	t.Run("Missing files fail the expectation", func(t *testing.T) {
+		s := &Server{}
+		exp := s.ExpectReq("GET", "/fixture").RespondWithFile(200, filepath.Join(dir, "missing.json"))
+
+		rec := serve(s, "GET", "/fixture")
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("Status: got %d, want 500", rec.Code)
+		}
+		if s.Pass() {
+			t.Errorf("Expected a missing fixture to fail the expectation")
+		}
+		if len(exp.failures) != 1 {
+			t.Errorf("Expected one failure, got %q", exp.failures)
+		}
+	})
+}
+
+func TestRespondFromDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "GET/users.json", `[]`)
+	writeFixture(t, dir, "GET/users/123.json", `{"id": 123}`)
+	writeFixture(t, dir, "GET/users/index.json", `index`)
+	writeFixture(t, dir, "POST/users.xml", `<created/>`)
+	writeFixture(t, dir, "GET/robots.txt", `User-agent: *`)
+	writeFixture(t, dir, "secret.json", `secret`)
+
+	testCases := []struct {
+		method, path string
+		body         string
+	}{
+		{"GET", "/users", `[]`},
+		{"GET", "/users/123", `{"id": 123}`},
+		{"GET", "/users/", `index`},
+		{"POST", "/users", `<created/>`},
+		{"GET", "/robots.txt", `User-agent: *`},
+	}
+
+	s := NewServer(t, nil)
+	s.ExpectReq(Any, Any).RespondFromDir(dir)
+
+	for _, tc := range testCases {
+		rec := serve(s, tc.method, tc.path)
+		if rec.Code != 200 {
+			t.Errorf("%s %s: got status %d, want 200", tc.method, tc.path, rec.Code)
+		}
+		if got, _ := io.ReadAll(rec.Body); string(got) != tc.body {
+			t.Errorf("%s %s: got body %q, want %q", tc.method, tc.path, got, tc.body)
+		}
+	}
+
+	t.Run("Methods cannot escape the fixture directory", func(t *testing.T) {
+		root := t.TempDir()
+		writeFixture(t, root, "secret.json", `secret`)
+		writeFixture(t, root, "fixtures/GET/users.json", `[]`)
+
+		s := &Server{}
+		exp := s.ExpectReq(Any, Any).RespondFromDir(filepath.Join(root, "fixtures"))
+
+		for _, method := range []string{"..", "."} {
+			rec := serve(s, method, "/secret")
+			if rec.Code != http.StatusInternalServerError {
+				t.Errorf("%s: got status %d, want 500", method, rec.Code)
+			}
+		}
+		if len(exp.failures) != 2 || exp.failures[0] != `no fixture for .. /secret, invalid method ".."` {
+			t.Errorf("Got failures %q", exp.failures)
+		}
+	})
+
+	t.Run("Missing fixtures fail the expectation", func(t *testing.T) {
+		s := &Server{}
+		exp := s.ExpectReq(Any, Any).RespondFromDir(dir)
+
+		for _, path := range []string{"/users/456", "/../secret"} {
+			rec := serve(s, "GET", path)
+			if rec.Code != http.StatusInternalServerError {
+				t.Errorf("%s: got status %d, want 500", path, rec.Code)
+			}
+		}
+
+		want := []string{
+			"no fixture for GET /users/456, expected " + filepath.Join(dir, "GET", "users", "456") + ".*",
+			"no fixture for GET /../secret, expected " + filepath.Join(dir, "GET", "secret") + ".*",
+		}
+		if len(exp.failures) != len(want) {
+			t.Fatalf("Got failures %q, want %q", exp.failures, want)
+		}
+		for i := range want {
+			if exp.failures[i] != want[i] {
+				t.Errorf("Got failure %q, want %q", exp.failures[i], want[i])
+			}
+		}
+	})
+}
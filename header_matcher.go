@@ -4,32 +4,107 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
-// WithHeader adds matching conditions against a request's headers
+// WithHeader adds matching conditions against a request's headers.
+//
+// Literal header names are canonicalized with http.CanonicalHeaderKey, so WithHeader("content-type") matches a
+// Content-Type header. Use Fold to match values case-insensitively. Headers whose values are comma-separated lists,
+// such as Accept and Cache-Control, match if either the whole value or any one of its elements matches:
+//
+//   WithHeader("cache-control", "no-store")     // matches Cache-Control: no-cache, no-store
+//   WithHeader("X-Mode", hex.Fold("debug"))     // matches X-Mode: DEBUG
 func (exp *Expectation) WithHeader(args ...interface{}) *Expectation {
+	return exp.withHeader("WithHeader", args, false)
+}
+
+// WithExactHeader matches against a request's headers like WithHeader, but additionally fails if the request contains
+// any headers, or any values for a header, that aren't mentioned by the arguments. Note that http.Client adds headers
+// such as User-Agent and Accept-Encoding to every request.
+func (exp *Expectation) WithExactHeader(args ...interface{}) *Expectation {
+	return exp.withHeader("WithExactHeader", args, true)
+}
+
+func (exp *Expectation) withHeader(name string, args []interface{}, exact bool) *Expectation {
+	matcher, err := makeHeaderMatcher(args, exact)
+	if err != nil {
+		panic(fmt.Sprintf("%s: %s", name, err.Error()))
+	}
+	return exp.addMatcher(matcher)
+}
+
+func makeHeaderMatcher(args []interface{}, exact bool) (*headerMatcher, error) {
 	matcher, err := makeURLValuesMatcher(args)
 	if err != nil {
-		panic(fmt.Sprintf("WithHeader: %s", err.Error()))
+		return nil, err
 	}
-	exp.matchers = append(exp.matchers, &headerMatcher{
+	matcher.forHeaders()
+
+	return &headerMatcher{
 		args:             args,
+		exact:            exact,
 		urlValuesMatcher: matcher,
-	})
-	return exp
+	}, nil
 }
 
 type headerMatcher struct {
-	args []interface{}
+	args  []interface{}
+	exact bool
 	urlValuesMatcher
 }
 
 var _ matcher = &headerMatcher{}
 
-func (h *headerMatcher) matches(req *http.Request) bool {
-	return h.urlValuesMatcher.matches(url.Values(req.Header))
+func (h *headerMatcher) matches(req *http.Request) matchResult {
+	return h.urlValuesMatcher.result(url.Values(req.Header), h.exact)
 }
 
 func (h *headerMatcher) String() string {
+	if h.exact {
+		return fmt.Sprintf("header exactly matching %v", matcherArgsToString(h.args))
+	}
 	return fmt.Sprintf("header matching %v", matcherArgsToString(h.args))
 }
+
+// forHeaders adapts the matcher to http.Header, whose keys are canonicalized and whose values may be comma-separated
+// lists
+func (u *urlValuesMatcher) forHeaders() {
+	u.tokenize = true
+	for i := range u.pairs {
+		u.pairs[i].key = canonicalHeaderKeyMatcher(u.pairs[i].key)
+	}
+	for i := range u.alts {
+		u.alts[i].forHeaders()
+	}
+}
+
+// canonicalHeaderKeyMatcher canonicalizes literal header names, including those combined by a LogicalMatcher
+func canonicalHeaderKeyMatcher(m stringMatcher) stringMatcher {
+	switch m := m.(type) {
+	case *stringLiteralMatcher:
+		return &stringLiteralMatcher{str: http.CanonicalHeaderKey(m.str)}
+	case *logicalStringMatcher:
+		canonical := &logicalStringMatcher{op: m.op}
+		for _, nested := range m.matchers {
+			canonical.matchers = append(canonical.matchers, canonicalHeaderKeyMatcher(nested))
+		}
+		return canonical
+	}
+	return m
+}
+
+// splitTokens splits a comma-separated header value into its elements, ie "no-cache, no-store" into "no-cache" and
+// "no-store". Values without commas have no tokens.
+func splitTokens(value string) []string {
+	if !strings.Contains(value, ",") {
+		return nil
+	}
+	var tokens []string
+	for _, token := range strings.Split(value, ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
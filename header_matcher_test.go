@@ -3,6 +3,8 @@ package hex
 import (
 	"fmt"
 	"net/http/httptest"
+	"strings"
+	"testing"
 )
 
 func ExampleExpectation_WithHeader() {
@@ -20,3 +22,113 @@ func ExampleExpectation_WithHeader() {
 	// Expectations
 	//	GET /foo with header matching Authorization="^Bearer .+$" - passed
 }
+
+func TestExactHeaderMatcher(t *testing.T) {
+	e := Expecter{}
+	exp := e.ExpectReq("GET", "/foo").WithExactHeader(P{"Authorization": R("^Bearer ")})
+
+	req := httptest.NewRequest("GET", "/foo", nil)
+	req.Header.Set("Authorization", "Bearer foobar")
+	req.Header.Set("X-Debug", "1")
+	e.LogReq(req)
+
+	if exp.closest == nil {
+		t.Fatalf("Expected %s to fail", exp.String())
+	}
+	if got, want := exp.closest.checks[2].actual, `got unexpected key "X-Debug"`; got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+}
+
+func TestHeaderMatcher(t *testing.T) {
+	testCases := []struct {
+		args   []interface{}
+		header map[string]string
+		want   bool
+	}{
+		// Literal keys are canonicalized
+		{[]interface{}{"content-type", "text/plain"}, map[string]string{"Content-Type": "text/plain"}, true},
+		{[]interface{}{"CONTENT-TYPE"}, map[string]string{"Content-Type": "text/plain"}, true},
+		{[]interface{}{P{"x-request-id": Any}}, map[string]string{"X-Request-Id": "1"}, true},
+		{[]interface{}{AnyOf("x-api-key", "authorization")}, map[string]string{"Authorization": "x"}, true},
+		{[]interface{}{Not("x-debug")}, map[string]string{"X-Debug": "1"}, false},
+
+		// Values are case-sensitive unless Fold is used
+		{[]interface{}{"X-Mode", "debug"}, map[string]string{"X-Mode": "DEBUG"}, false},
+		{[]interface{}{"X-Mode", Fold("debug")}, map[string]string{"X-Mode": "DEBUG"}, true},
+		{[]interface{}{"X-Mode", Fold("debug")}, map[string]string{"X-Mode": "debugging"}, false},
+		{[]interface{}{Fold("x-mode")}, map[string]string{"X-Mode": "1"}, true},
+
+		// Comma-separated values match as a whole, or by any element
+		{[]interface{}{"Cache-Control", "no-store"}, map[string]string{"Cache-Control": "no-cache, no-store"}, true},
+		{[]interface{}{"Cache-Control", "no-cache, no-store"}, map[string]string{"Cache-Control": "no-cache, no-store"}, true},
+		{[]interface{}{"Cache-Control", "private"}, map[string]string{"Cache-Control": "no-cache, no-store"}, false},
+		{[]interface{}{"Accept", "application/json"}, map[string]string{"Accept": "text/html,application/json"}, true},
+		{[]interface{}{"Accept", R("^application/")}, map[string]string{"Accept": "text/html, application/json"}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%v %v", tc.args, tc.header), func(t *testing.T) {
+			e := Expecter{}
+			e.ExpectReq("GET", "/").WithHeader(tc.args...)
+
+			req := httptest.NewRequest("GET", "/", nil)
+			for key, value := range tc.header {
+				req.Header.Set(key, value)
+			}
+			e.LogReq(req)
+
+			if got := e.Pass(); got != tc.want {
+				t.Errorf("Got %t, want %t\n%s", got, tc.want, e.Summary())
+			}
+		})
+	}
+}
+
+func TestExactHeaderMatcherTokens(t *testing.T) {
+	testCases := []struct {
+		header string
+		args   []interface{}
+		values []string
+		actual string
+	}{
+		{"Cache-Control", []interface{}{"cache-control", AnyOf("no-cache", "no-store")}, []string{"no-cache, no-store"}, ""},
+		{"Cache-Control", []interface{}{"cache-control", "no-cache, no-store"}, []string{"no-cache, no-store"}, ""},
+		{"Cache-Control", []interface{}{"cache-control", "no-cache"}, []string{"no-cache, no-store"}, `got unexpected value "no-store" for key "Cache-Control"`},
+		{"X-Mode", []interface{}{P{"X-Mode": "a"}}, []string{"a", "b"}, `got unexpected value "b" for key "X-Mode"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(strings.Join(tc.values, "; "), func(t *testing.T) {
+			e := Expecter{}
+			exp := e.ExpectReq("GET", "/").WithExactHeader(tc.args...)
+
+			req := httptest.NewRequest("GET", "/", nil)
+			for _, value := range tc.values {
+				req.Header.Add(tc.header, value)
+			}
+			e.LogReq(req)
+
+			if tc.actual == "" {
+				if !e.Pass() {
+					t.Errorf("Expected to pass\n%s", e.Summary())
+				}
+			} else if exp.closest == nil || exp.closest.checks[2].actual != tc.actual {
+				t.Errorf("Expected failure %q\n%s", tc.actual, e.Summary())
+			}
+		})
+	}
+}
+
+func TestHeaderParams(t *testing.T) {
+	e := Expecter{}
+	e.ExpectReq(P{"header": P{"content-type": Fold("TEXT/PLAIN")}})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Content-Type", "text/plain")
+	e.LogReq(req)
+
+	if !e.Pass() {
+		t.Errorf("Expected header condition given to ExpectReq to be canonicalized\n%s", e.Summary())
+	}
+}
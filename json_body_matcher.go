@@ -0,0 +1,85 @@
+package hex
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// WithJSONBody adds matching conditions against a request's JSON-encoded body.
+// The body is decoded and matched against want, which is typically a hex.P, possibly containing nested hex.P's and
+// slices. Each leaf value may be a string, regular expression, hex.Any, hex.None, number, bool or nil:
+//
+//   WithJSONBody(hex.P{"name": "bob"}) // passes {"name": "bob", "age": 42}
+//   WithJSONBody(hex.P{"user": hex.P{"age": hex.R(`^\d+$`)}}) // nested objects
+//   WithJSONBody(hex.P{"roles": []interface{}{"admin", hex.Any}}) // arrays must match in length and order
+//   WithJSONBody(hex.P{"age": 42, "admin": true, "deleted_at": nil}) // numbers, booleans and null
+//
+// Objects are partially matched: keys present in the request but not mentioned in want are ignored.
+// Use WithExactJSONBody to reject unexpected keys.
+func (e *Expectation) WithJSONBody(want interface{}) *Expectation {
+	return e.withJSONBody("WithJSONBody", want, false)
+}
+
+// WithExactJSONBody is like WithJSONBody, but fails if any object in the request body contains keys that are not
+// present in want
+func (e *Expectation) WithExactJSONBody(want interface{}) *Expectation {
+	return e.withJSONBody("WithExactJSONBody", want, true)
+}
+
+func (e *Expectation) withJSONBody(name string, want interface{}, exact bool) *Expectation {
+	matcher, err := makeJSONMatcher(want, exact)
+	if err != nil {
+		panic(fmt.Sprintf("%s: %s", name, err.Error()))
+	}
+
+	return e.addMatcher(&jsonBodyMatcher{
+		exact:       exact,
+		jsonMatcher: matcher,
+	})
+}
+
+type jsonBodyMatcher struct {
+	exact       bool
+	jsonMatcher jsonMatcher
+}
+
+var _ matcher = &jsonBodyMatcher{}
+
+func (j *jsonBodyMatcher) matches(req *http.Request) matchResult {
+	value, err := readJSONBody(req)
+	if err != nil {
+		return mismatch("%s", err.Error())
+	}
+
+	if err := j.jsonMatcher.matchJSON("$", value); err != nil {
+		return mismatch("%s", err.Error())
+	}
+
+	return matchSuccess
+}
+
+// readJSONBody decodes the request's body as JSON
+func readJSONBody(req *http.Request) (interface{}, error) {
+	if req.Body == nil {
+		return nil, fmt.Errorf("request has no body")
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read request body: %s", err.Error())
+	}
+
+	value, err := decodeJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("request body is not valid JSON: %s", err.Error())
+	}
+	return value, nil
+}
+
+func (j *jsonBodyMatcher) String() string {
+	if j.exact {
+		return fmt.Sprintf("JSON body exactly matching %s", j.jsonMatcher.String())
+	}
+	return fmt.Sprintf("JSON body matching %s", j.jsonMatcher.String())
+}
@@ -0,0 +1,89 @@
+package hex
+
+import (
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func ExampleExpectation_WithJSONBody() {
+	e := Expecter{}
+
+	e.ExpectReq("POST", "/users").WithJSONBody(P{
+		"name":  "bob",
+		"roles": []interface{}{"admin", Any},
+	})
+
+	e.LogReq(httptest.NewRequest("POST", "/users", strings.NewReader(`{"name": "bob", "age": 42, "roles": ["admin", "user"]}`)))
+
+	fmt.Println(e.Summary())
+	// Output:
+	// Expectations
+	// 	POST /users with JSON body matching {"name": "bob", "roles": ["admin", <any>]} - passed
+}
+
+func ExampleExpectation_WithJSONBody_mismatch() {
+	e := Expecter{}
+
+	e.ExpectReq("POST", "/users").WithJSONBody(P{"user": P{"name": "bob"}})
+
+	e.LogReq(httptest.NewRequest("POST", "/users", strings.NewReader(`{"user": {"name": "sam"}}`)))
+
+	fmt.Println(e.Summary())
+	// Output:
+	// Expectations
+	// 	POST /users with JSON body matching {"user": {"name": "bob"}} - failed, no matching requests
+	// 		closest unmatched request: POST /users
+	// 			method POST: passed
+	// 			path /users: passed
+	// 			JSON body matching {"user": {"name": "bob"}}: failed, $.user.name: expected "bob", got "sam"
+	// Unmatched Requests
+	// 	POST /users
+}
+
+func TestJSONBodyMatcher(t *testing.T) {
+	t.Run("It matches JSON request bodies", func(t *testing.T) {
+		e := Expecter{}
+		e.ExpectReq("POST", "/users").WithJSONBody(P{"name": "bob"})
+		e.LogReq(httptest.NewRequest("POST", "/users", strings.NewReader(`{"name": "bob"}`)))
+		assertPassed(t, 1, &e)
+	})
+
+	t.Run("It fails on invalid JSON", func(t *testing.T) {
+		e := Expecter{}
+		e.ExpectReq("POST", "/users").WithJSONBody(P{"name": "bob"})
+		e.LogReq(httptest.NewRequest("POST", "/users", strings.NewReader(`name=bob`)))
+		assertFailed(t, 1, &e)
+	})
+
+	t.Run("WithExactJSONBody rejects unexpected keys", func(t *testing.T) {
+		e := Expecter{}
+		e.ExpectReq("POST", "/users").WithExactJSONBody(P{"name": "bob"})
+		e.LogReq(httptest.NewRequest("POST", "/users", strings.NewReader(`{"name": "bob", "debug": true}`)))
+		assertFailed(t, 1, &e)
+	})
+
+	t.Run("The body can still be read after matching", func(t *testing.T) {
+		e := Expecter{}
+		e.ExpectReq("POST", "/users").WithJSONBody(P{"name": "bob"})
+		req := httptest.NewRequest("POST", "/users", strings.NewReader(`{"name": "bob"}`))
+		e.LogReq(req)
+
+		body, err := io.ReadAll(req.Body)
+		if err != nil || string(body) != `{"name": "bob"}` {
+			t.Errorf("Expected body to be readable after matching, got %q (%v)", body, err)
+		}
+	})
+
+	t.Run("Invalid expectations panic", func(t *testing.T) {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Errorf("Expected WithJSONBody with an invalid argument to panic")
+			}
+		}()
+		e := Expecter{}
+		e.ExpectReq("POST", "/users").WithJSONBody(make(chan int))
+	})
+}
@@ -0,0 +1,401 @@
+package hex
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// jsonMatcher matches a single decoded JSON value. Decoded values are those produced by encoding/json when decoding
+// into an interface{} with UseNumber enabled: map[string]interface{}, []interface{}, string, json.Number, bool or nil.
+type jsonMatcher interface {
+	// matchJSON returns nil if value matches, or an error describing the mismatch. path is the JSON path of value,
+	// starting with "$" for the document root, and is used to report where a mismatch occurred.
+	matchJSON(path string, value interface{}) error
+	String() string
+}
+
+// makeJSONMatcher builds a jsonMatcher from an expected value, which may be:
+//
+//   - A string, Fold, regular expression, Path or StringMatcher, which matches JSON strings
+//   - Any or None
+//   - A number (any int, uint or float type, or a json.Number), which matches JSON numbers of equal value
+//   - A bool, which matches JSON true/false
+//   - nil, which matches JSON null
+//   - A P or other map with string keys, which matches JSON objects
+//   - A slice or array, which matches JSON arrays of the same length, element by element
+//   - Any other value which can be marshalled to JSON, such as a struct
+//
+// Maps and slices may contain any of the above. When exact is false, objects in the request may contain keys that
+// are not mentioned by the expected value. When exact is true, they may not.
+func makeJSONMatcher(want interface{}, exact bool) (jsonMatcher, error) {
+	switch v := want.(type) {
+	case nil:
+		return &jsonNullMatcher{}, nil
+	case MatchConst:
+		if v == Any {
+			return &jsonAnyMatcher{}, nil
+		} else if v == None {
+			return &jsonNoneMatcher{}, nil
+		}
+		return nil, fmt.Errorf("Cannot use value %v when matching against JSON", want)
+	case string, *regexp.Regexp, func(string) bool, StringMatcher, Path, Fold:
+		return &jsonStringMatcher{matcher: mustMakeStringMatcher(v)}, nil
+	case bool:
+		return &jsonBoolMatcher{want: v}, nil
+	case json.Number:
+		return &jsonNumberMatcher{want: v}, nil
+	case P:
+		return makeJSONObjectMatcher(v, exact)
+	}
+
+	rv := reflect.ValueOf(want)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return &jsonNumberMatcher{want: json.Number(fmt.Sprint(want))}, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String && rv.Type().Key().Kind() != reflect.Interface {
+			break
+		}
+		params := make(P, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			params[iter.Key().Interface()] = iter.Value().Interface()
+		}
+		return makeJSONObjectMatcher(params, exact)
+	case reflect.Slice, reflect.Array:
+		elems := make([]jsonMatcher, rv.Len())
+		for i := range elems {
+			m, err := makeJSONMatcher(rv.Index(i).Interface(), exact)
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = m
+		}
+		return &jsonArrayMatcher{elems: elems}, nil
+	}
+
+	// Fall back to round-tripping the value through encoding/json, which lets structs and other marshallable values
+	// be used as expectations
+	encoded, err := json.Marshal(want)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot use value %v when matching against JSON", want)
+	}
+	decoded, err := decodeJSON(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot use value %v when matching against JSON", want)
+	}
+	return makeJSONMatcher(decoded, exact)
+}
+
+func mustMakeJSONMatcher(want interface{}, exact bool) jsonMatcher {
+	if m, err := makeJSONMatcher(want, exact); err != nil {
+		panic(err)
+	} else {
+		return m
+	}
+}
+
+func makeJSONObjectMatcher(params P, exact bool) (jsonMatcher, error) {
+	m := &jsonObjectMatcher{exact: exact}
+	for key, value := range params {
+		keyMatcher, err := makeStringMatcher(key)
+		if err != nil {
+			return nil, err
+		}
+		valueMatcher, err := makeJSONMatcher(value, exact)
+		if err != nil {
+			return nil, err
+		}
+		m.pairs = append(m.pairs, jsonKeyValueMatcher{key: keyMatcher, value: valueMatcher})
+	}
+
+	// Map iteration order is random, but we want matching and String() to be deterministic
+	sort.Slice(m.pairs, func(i, j int) bool {
+		return m.pairs[i].key.String() < m.pairs[j].key.String()
+	})
+
+	return m, nil
+}
+
+// decodeJSON decodes a single JSON document, preserving numbers as json.Number
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after top-level JSON value")
+	}
+	return value, nil
+}
+
+// jsonString renders a decoded JSON value for use in mismatch messages
+func jsonString(value interface{}) string {
+	if encoded, err := json.Marshal(value); err == nil {
+		return string(encoded)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// jsonTypeName names the type of a decoded JSON value for use in mismatch messages
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func jsonMismatch(path string, want fmt.Stringer, got interface{}) error {
+	return fmt.Errorf("%s: expected %s, got %s", path, want.String(), jsonString(got))
+}
+
+type jsonAnyMatcher struct{}
+
+var _ jsonMatcher = &jsonAnyMatcher{}
+
+func (*jsonAnyMatcher) matchJSON(string, interface{}) error {
+	return nil
+}
+
+func (*jsonAnyMatcher) String() string {
+	return "<any>"
+}
+
+type jsonNoneMatcher struct{}
+
+var _ jsonMatcher = &jsonNoneMatcher{}
+
+func (m *jsonNoneMatcher) matchJSON(path string, value interface{}) error {
+	return jsonMismatch(path, m, value)
+}
+
+func (*jsonNoneMatcher) String() string {
+	return "<none>"
+}
+
+type jsonNullMatcher struct{}
+
+var _ jsonMatcher = &jsonNullMatcher{}
+
+func (m *jsonNullMatcher) matchJSON(path string, value interface{}) error {
+	if value != nil {
+		return jsonMismatch(path, m, value)
+	}
+	return nil
+}
+
+func (*jsonNullMatcher) String() string {
+	return "null"
+}
+
+type jsonStringMatcher struct {
+	matcher stringMatcher
+}
+
+var _ jsonMatcher = &jsonStringMatcher{}
+
+func (m *jsonStringMatcher) matchJSON(path string, value interface{}) error {
+	if str, ok := value.(string); !ok || !m.matcher.match(str) {
+		return jsonMismatch(path, m, value)
+	}
+	return nil
+}
+
+func (m *jsonStringMatcher) String() string {
+	return fmt.Sprintf("%q", m.matcher.String())
+}
+
+type jsonBoolMatcher struct {
+	want bool
+}
+
+var _ jsonMatcher = &jsonBoolMatcher{}
+
+func (m *jsonBoolMatcher) matchJSON(path string, value interface{}) error {
+	if b, ok := value.(bool); !ok || b != m.want {
+		return jsonMismatch(path, m, value)
+	}
+	return nil
+}
+
+func (m *jsonBoolMatcher) String() string {
+	return fmt.Sprintf("%t", m.want)
+}
+
+type jsonNumberMatcher struct {
+	want json.Number
+}
+
+var _ jsonMatcher = &jsonNumberMatcher{}
+
+func (m *jsonNumberMatcher) matchJSON(path string, value interface{}) error {
+	got, ok := value.(json.Number)
+	if !ok {
+		return jsonMismatch(path, m, value)
+	}
+
+	// Compare integers as integers where possible, so large values don't lose precision
+	if wantInt, err := m.want.Int64(); err == nil {
+		if gotInt, err := got.Int64(); err == nil {
+			if wantInt != gotInt {
+				return jsonMismatch(path, m, value)
+			}
+			return nil
+		}
+	}
+
+	wantFloat, err := m.want.Float64()
+	if err != nil {
+		return jsonMismatch(path, m, value)
+	}
+	if gotFloat, err := got.Float64(); err != nil || gotFloat != wantFloat {
+		return jsonMismatch(path, m, value)
+	}
+	return nil
+}
+
+func (m *jsonNumberMatcher) String() string {
+	return m.want.String()
+}
+
+type jsonArrayMatcher struct {
+	elems []jsonMatcher
+}
+
+var _ jsonMatcher = &jsonArrayMatcher{}
+
+func (m *jsonArrayMatcher) matchJSON(path string, value interface{}) error {
+	arr, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("%s: expected array, got %s", path, jsonTypeName(value))
+	}
+
+	if len(arr) != len(m.elems) {
+		return fmt.Errorf("%s: expected array of length %d, got length %d", path, len(m.elems), len(arr))
+	}
+
+	for i, elem := range m.elems {
+		if err := elem.matchJSON(fmt.Sprintf("%s[%d]", path, i), arr[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *jsonArrayMatcher) String() string {
+	parts := make([]string, len(m.elems))
+	for i, elem := range m.elems {
+		parts[i] = elem.String()
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+type jsonKeyValueMatcher struct {
+	key   stringMatcher
+	value jsonMatcher
+}
+
+type jsonObjectMatcher struct {
+	pairs []jsonKeyValueMatcher
+	exact bool
+}
+
+var _ jsonMatcher = &jsonObjectMatcher{}
+
+func (m *jsonObjectMatcher) matchJSON(path string, value interface{}) error {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s: expected object, got %s", path, jsonTypeName(value))
+	}
+
+	for _, pair := range m.pairs {
+		// Literal keys are looked up directly, which lets us report the mismatch of the nested value
+		if literal, ok := pair.key.(*stringLiteralMatcher); ok {
+			nested, found := obj[literal.str]
+			if !found {
+				return fmt.Errorf("%s: missing key %q", path, literal.str)
+			}
+			if err := pair.value.matchJSON(jsonPathChild(path, literal.str), nested); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Otherwise, at least one key must match the key matcher and have a matching value
+		found := false
+		for _, key := range sortedJSONKeys(obj) {
+			if pair.key.match(key) && pair.value.matchJSON(jsonPathChild(path, key), obj[key]) == nil {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: no key matching %q with value matching %s", path, pair.key.String(), pair.value.String())
+		}
+	}
+
+	if m.exact {
+		for _, key := range sortedJSONKeys(obj) {
+			expected := false
+			for _, pair := range m.pairs {
+				if pair.key.match(key) {
+					expected = true
+					break
+				}
+			}
+			if !expected {
+				return fmt.Errorf("%s: unexpected key %q", path, key)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *jsonObjectMatcher) String() string {
+	parts := make([]string, len(m.pairs))
+	for i, pair := range m.pairs {
+		parts[i] = fmt.Sprintf("%q: %s", pair.key.String(), pair.value.String())
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func sortedJSONKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var jsonIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// jsonPathChild returns the path of the given key within the object at path, ie $.user.name or $["first name"]
+func jsonPathChild(path, key string) string {
+	if jsonIdentifier.MatchString(key) {
+		return path + "." + key
+	}
+	return fmt.Sprintf("%s[%q]", path, key)
+}
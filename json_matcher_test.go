@@ -0,0 +1,134 @@
+package hex
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestJSONMatcher(t *testing.T) {
+	type User struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	testCases := []struct {
+		want  interface{}
+		exact bool
+		body  string
+		pass  bool
+	}{
+		// Scalars
+		{"bob", false, `"bob"`, true},
+		{"bob", false, `"sam"`, false},
+		{"1", false, `1`, false},
+		{R(`^\d+$`), false, `"123"`, true},
+		{R(`^\d+$`), false, `123`, false},
+		{42, false, `42`, true},
+		{42, false, `42.0`, true},
+		{42, false, `43`, false},
+		{42, false, `"42"`, false},
+		{1.5, false, `1.5`, true},
+		{int64(9007199254740993), false, `9007199254740993`, true},
+		{int64(9007199254740993), false, `9007199254740992`, false},
+		{true, false, `true`, true},
+		{true, false, `false`, false},
+		{nil, false, `null`, true},
+		{nil, false, `0`, false},
+		{Any, false, `null`, true},
+		{Any, false, `{"a": 1}`, true},
+		{None, false, `"x"`, false},
+
+		// Objects are partially matched by default
+		{P{"name": "bob"}, false, `{"name": "bob", "age": 42}`, true},
+		{P{"name": "bob"}, false, `{"name": "sam"}`, false},
+		{P{"name": "bob"}, false, `{"age": 42}`, false},
+		{P{"name": "bob"}, false, `["bob"]`, false},
+		{P{"name": "bob", "age": 42}, false, `{"name": "bob", "age": 42}`, true},
+		{P{"name": Any}, false, `{"name": null}`, true},
+		{P{"name": Any}, false, `{}`, false},
+		{P{R(`^na`): "bob"}, false, `{"age": 1, "name": "bob"}`, true},
+		{P{R(`^na`): "bob"}, false, `{"nap": "sam", "name": "bob"}`, true},
+		{P{R(`^na`): "bob"}, false, `{"name": "sam"}`, false},
+		{map[string]interface{}{"name": "bob"}, false, `{"name": "bob"}`, true},
+
+		// Exact object matching
+		{P{"name": "bob"}, true, `{"name": "bob"}`, true},
+		{P{"name": "bob"}, true, `{"name": "bob", "age": 42}`, false},
+		{P{"user": P{"name": "bob"}}, true, `{"user": {"name": "bob", "age": 42}}`, false},
+		{P{R(`.`): Any}, true, `{"name": "bob", "age": 42}`, true},
+
+		// Nested objects
+		{P{"user": P{"name": "bob"}}, false, `{"user": {"name": "bob", "age": 42}}`, true},
+		{P{"user": P{"name": "bob"}}, false, `{"user": {"name": "sam"}}`, false},
+		{P{"user": P{"name": "bob"}}, false, `{"user": "bob"}`, false},
+
+		// Arrays
+		{[]interface{}{"a", "b"}, false, `["a", "b"]`, true},
+		{[]interface{}{"a", "b"}, false, `["a"]`, false},
+		{[]interface{}{"a", "b"}, false, `["b", "a"]`, false},
+		{[]interface{}{"a", Any}, false, `["a", "b"]`, true},
+		{[]int{1, 2}, false, `[1, 2]`, true},
+		{P{"roles": []interface{}{P{"name": "admin"}}}, false, `{"roles": [{"name": "admin", "id": 1}]}`, true},
+		{P{"roles": []interface{}{P{"name": "admin"}}}, false, `{"roles": [{"name": "user"}]}`, false},
+
+		// Structs are round-tripped through encoding/json
+		{User{Name: "bob", Age: 42}, false, `{"name": "bob", "age": 42, "admin": true}`, true},
+		{User{Name: "bob", Age: 42}, true, `{"name": "bob", "age": 42, "admin": true}`, false},
+		{User{Name: "bob", Age: 42}, false, `{"name": "bob", "age": 43}`, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("Matching %v (exact: %t) against %s", tc.want, tc.exact, tc.body), func(t *testing.T) {
+			m, err := makeJSONMatcher(tc.want, tc.exact)
+			if err != nil {
+				t.Fatalf("Unexpected error creating JSON matcher for %v: %s", tc.want, err)
+			}
+
+			value, err := decodeJSON([]byte(tc.body))
+			if err != nil {
+				t.Fatalf("Invalid JSON in test case: %s", tc.body)
+			}
+
+			err = m.matchJSON("$", value)
+			if got := err == nil; got != tc.pass {
+				t.Errorf("Got %t (%v), want %t", got, err, tc.pass)
+			}
+		})
+	}
+}
+
+func TestJSONMatcherMismatchPath(t *testing.T) {
+	testCases := []struct {
+		want interface{}
+		body string
+		err  string
+	}{
+		{P{"user": P{"name": "bob"}}, `{"user": {"name": "sam"}}`, `$.user.name: expected "bob", got "sam"`},
+		{P{"user": P{"name": "bob"}}, `{"user": {}}`, `$.user: missing key "name"`},
+		{P{"roles": []interface{}{"admin"}}, `{"roles": ["user"]}`, `$.roles[0]: expected "admin", got "user"`},
+		{P{"roles": []interface{}{"admin"}}, `{"roles": []}`, `$.roles: expected array of length 1, got length 0`},
+		{P{"first name": "bob"}, `{"first name": 1}`, `$["first name"]: expected "bob", got 1`},
+		{P{"user": P{"age": 42}}, `{"user": []}`, `$.user: expected object, got array`},
+	}
+
+	for _, tc := range testCases {
+		m := mustMakeJSONMatcher(tc.want, false)
+		value, err := decodeJSON([]byte(tc.body))
+		if err != nil {
+			t.Fatalf("Invalid JSON in test case: %s", tc.body)
+		}
+
+		err = m.matchJSON("$", value)
+		if err == nil {
+			t.Errorf("Matching %v against %s: expected an error, got nil", tc.want, tc.body)
+		} else if err.Error() != tc.err {
+			t.Errorf("Matching %v against %s: got error %q, want %q", tc.want, tc.body, err.Error(), tc.err)
+		}
+	}
+}
+
+func TestJSONMatcherInvalidArgs(t *testing.T) {
+	if _, err := makeJSONMatcher(func() {}, false); err == nil {
+		t.Errorf("Expected makeJSONMatcher with invalid arguments to return an error, but none was returned")
+	}
+}
@@ -0,0 +1,467 @@
+package hex
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a compiled JSONPath expression, used to select values from a decoded JSON document.
+//
+// The supported syntax is a subset of JSONPath:
+//
+//   $                     the document root; may be omitted, as in "user.name"
+//   .name or ['name']     an object member
+//   [0], [-1]             an array element, counting from the end if negative
+//   .* or [*]             all members of an object, or all elements of an array
+//   ..name or ..*         recursive descent
+//   [?(@.age > 18)]       array elements (or object members) matching a filter
+//
+// Filters compare a path relative to the current element (@) against a literal string, number, boolean or null using
+// ==, !=, <, <=, > or >=, or against a regular expression using =~. A filter with no operator, like [?(@.email)],
+// selects elements where the path exists.
+type jsonPath struct {
+	expr  string
+	steps []jsonPathStep
+}
+
+// jsonPathNode is a value selected by a jsonPath, along with its concrete path within the document
+type jsonPathNode struct {
+	path  string
+	value interface{}
+}
+
+type jsonPathStep interface {
+	apply(node jsonPathNode) []jsonPathNode
+}
+
+// compileJSONPath parses a JSONPath expression
+func compileJSONPath(expr string) (*jsonPath, error) {
+	src := strings.TrimSpace(expr)
+	if src == "" {
+		return nil, fmt.Errorf("empty JSON path")
+	}
+
+	// Allow plain dotted paths like "user.roles[0]" by treating them as relative to the root
+	if !strings.HasPrefix(src, "$") {
+		if strings.HasPrefix(src, "[") {
+			src = "$" + src
+		} else {
+			src = "$." + src
+		}
+	}
+
+	p := jsonPathParser{src: src, pos: 1}
+	steps, err := p.parseSteps(false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON path %q: %s", expr, err.Error())
+	}
+
+	return &jsonPath{expr: expr, steps: steps}, nil
+}
+
+// selectFrom returns all nodes in the document selected by the path
+func (p *jsonPath) selectFrom(doc interface{}) []jsonPathNode {
+	return applyJSONPathSteps(p.steps, jsonPathNode{path: "$", value: doc})
+}
+
+func (p *jsonPath) String() string {
+	return p.expr
+}
+
+func applyJSONPathSteps(steps []jsonPathStep, root jsonPathNode) []jsonPathNode {
+	nodes := []jsonPathNode{root}
+	for _, step := range steps {
+		var next []jsonPathNode
+		for _, node := range nodes {
+			next = append(next, step.apply(node)...)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// jsonPathChildren returns all direct children of an object or array, in a deterministic order
+func jsonPathChildren(node jsonPathNode) []jsonPathNode {
+	switch v := node.value.(type) {
+	case map[string]interface{}:
+		children := make([]jsonPathNode, 0, len(v))
+		for _, key := range sortedJSONKeys(v) {
+			children = append(children, jsonPathNode{path: jsonPathChild(node.path, key), value: v[key]})
+		}
+		return children
+	case []interface{}:
+		children := make([]jsonPathNode, 0, len(v))
+		for i, elem := range v {
+			children = append(children, jsonPathNode{path: fmt.Sprintf("%s[%d]", node.path, i), value: elem})
+		}
+		return children
+	}
+	return nil
+}
+
+type jsonPathMemberStep struct {
+	name string
+}
+
+func (s *jsonPathMemberStep) apply(node jsonPathNode) []jsonPathNode {
+	if obj, ok := node.value.(map[string]interface{}); ok {
+		if value, found := obj[s.name]; found {
+			return []jsonPathNode{{path: jsonPathChild(node.path, s.name), value: value}}
+		}
+	}
+	return nil
+}
+
+type jsonPathIndexStep struct {
+	index int
+}
+
+func (s *jsonPathIndexStep) apply(node jsonPathNode) []jsonPathNode {
+	arr, ok := node.value.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	index := s.index
+	if index < 0 {
+		index += len(arr)
+	}
+	if index < 0 || index >= len(arr) {
+		return nil
+	}
+	return []jsonPathNode{{path: fmt.Sprintf("%s[%d]", node.path, index), value: arr[index]}}
+}
+
+type jsonPathWildcardStep struct{}
+
+func (s *jsonPathWildcardStep) apply(node jsonPathNode) []jsonPathNode {
+	return jsonPathChildren(node)
+}
+
+// jsonPathDescendantStep applies its step to the node and all of its descendants
+type jsonPathDescendantStep struct {
+	step jsonPathStep
+}
+
+func (s *jsonPathDescendantStep) apply(node jsonPathNode) []jsonPathNode {
+	selected := s.step.apply(node)
+	for _, child := range jsonPathChildren(node) {
+		selected = append(selected, s.apply(child)...)
+	}
+	return selected
+}
+
+type jsonPathFilterStep struct {
+	filter *jsonPathFilter
+}
+
+func (s *jsonPathFilterStep) apply(node jsonPathNode) []jsonPathNode {
+	var selected []jsonPathNode
+	for _, child := range jsonPathChildren(node) {
+		if s.filter.test(child) {
+			selected = append(selected, child)
+		}
+	}
+	return selected
+}
+
+type jsonPathFilter struct {
+	steps   []jsonPathStep
+	op      string
+	literal interface{}
+	pattern *regexp.Regexp
+}
+
+func (f *jsonPathFilter) test(node jsonPathNode) bool {
+	selected := applyJSONPathSteps(f.steps, node)
+	if f.op == "" {
+		return len(selected) > 0
+	}
+
+	for _, candidate := range selected {
+		if f.compare(candidate.value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *jsonPathFilter) compare(value interface{}) bool {
+	if f.op == "=~" {
+		str, ok := value.(string)
+		return ok && f.pattern.MatchString(str)
+	}
+
+	// Numbers are compared numerically, everything else must be of the same type
+	if got, ok := value.(json.Number); ok {
+		want, ok := f.literal.(json.Number)
+		if !ok {
+			return f.op == "!="
+		}
+		gotFloat, err1 := got.Float64()
+		wantFloat, err2 := want.Float64()
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		return compareOrdered(f.op, gotFloat < wantFloat, gotFloat == wantFloat)
+	}
+
+	if got, ok := value.(string); ok {
+		want, ok := f.literal.(string)
+		if !ok {
+			return f.op == "!="
+		}
+		return compareOrdered(f.op, got < want, got == want)
+	}
+
+	// Booleans, null, objects and arrays only support equality
+	equal := jsonString(value) == jsonString(f.literal)
+	switch f.op {
+	case "==":
+		return equal
+	case "!=":
+		return !equal
+	}
+	return false
+}
+
+func compareOrdered(op string, less, equal bool) bool {
+	switch op {
+	case "==":
+		return equal
+	case "!=":
+		return !equal
+	case "<":
+		return less
+	case "<=":
+		return less || equal
+	case ">":
+		return !less && !equal
+	case ">=":
+		return !less
+	}
+	return false
+}
+
+type jsonPathParser struct {
+	src string
+	pos int
+}
+
+func (p *jsonPathParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *jsonPathParser) peek(s string) bool {
+	return strings.HasPrefix(p.src[p.pos:], s)
+}
+
+func (p *jsonPathParser) consume(s string) bool {
+	if p.peek(s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *jsonPathParser) skipSpace() {
+	for !p.eof() && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *jsonPathParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// parseSteps parses steps until the end of input or, when inFilter is true, until a filter operator or closing paren
+func (p *jsonPathParser) parseSteps(inFilter bool) ([]jsonPathStep, error) {
+	var steps []jsonPathStep
+	for !p.eof() {
+		if inFilter {
+			p.skipSpace()
+			if p.eof() || !p.peek(".") && !p.peek("[") {
+				break
+			}
+		}
+
+		var step jsonPathStep
+		var err error
+
+		switch {
+		case p.consume(".."):
+			if step, err = p.parseDottedStep(); err == nil {
+				step = &jsonPathDescendantStep{step: step}
+			}
+		case p.consume("."):
+			step, err = p.parseDottedStep()
+		case p.consume("["):
+			step, err = p.parseBracketStep()
+		default:
+			err = p.errorf("unexpected character %q", p.src[p.pos])
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+var jsonPathName = regexp.MustCompile(`^[^.\[\]\s()=!<>~&|]+`)
+
+func (p *jsonPathParser) parseDottedStep() (jsonPathStep, error) {
+	if p.consume("*") {
+		return &jsonPathWildcardStep{}, nil
+	}
+	if p.consume("[") {
+		return p.parseBracketStep()
+	}
+
+	name := jsonPathName.FindString(p.src[p.pos:])
+	if name == "" {
+		return nil, p.errorf("expected a member name")
+	}
+	p.pos += len(name)
+	return &jsonPathMemberStep{name: name}, nil
+}
+
+func (p *jsonPathParser) parseBracketStep() (jsonPathStep, error) {
+	p.skipSpace()
+
+	var step jsonPathStep
+	switch {
+	case p.consume("*"):
+		step = &jsonPathWildcardStep{}
+	case p.consume("?("):
+		filter, err := p.parseFilter()
+		if err != nil {
+			return nil, err
+		}
+		step = &jsonPathFilterStep{filter: filter}
+	case p.peek("'") || p.peek(`"`):
+		name, err := p.parseQuoted()
+		if err != nil {
+			return nil, err
+		}
+		step = &jsonPathMemberStep{name: name}
+	default:
+		start := p.pos
+		for !p.eof() && (p.src[p.pos] == '-' || p.src[p.pos] >= '0' && p.src[p.pos] <= '9') {
+			p.pos++
+		}
+		index, err := strconv.Atoi(p.src[start:p.pos])
+		if err != nil {
+			return nil, p.errorf("expected an index, name, wildcard or filter")
+		}
+		step = &jsonPathIndexStep{index: index}
+	}
+
+	p.skipSpace()
+	if !p.consume("]") {
+		return nil, p.errorf("expected ]")
+	}
+	return step, nil
+}
+
+func (p *jsonPathParser) parseQuoted() (string, error) {
+	quote := p.src[p.pos]
+	p.pos++
+
+	var buf strings.Builder
+	for !p.eof() {
+		c := p.src[p.pos]
+		p.pos++
+		switch {
+		case c == '\\' && !p.eof():
+			buf.WriteByte(p.src[p.pos])
+			p.pos++
+		case c == quote:
+			return buf.String(), nil
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+var jsonPathOperators = []string{"==", "!=", "<=", ">=", "=~", "<", ">"}
+
+func (p *jsonPathParser) parseFilter() (*jsonPathFilter, error) {
+	p.skipSpace()
+	if !p.consume("@") {
+		return nil, p.errorf("expected @ at start of filter")
+	}
+
+	steps, err := p.parseSteps(true)
+	if err != nil {
+		return nil, err
+	}
+	filter := &jsonPathFilter{steps: steps}
+
+	p.skipSpace()
+	for _, op := range jsonPathOperators {
+		if p.consume(op) {
+			filter.op = op
+			break
+		}
+	}
+
+	if filter.op != "" {
+		p.skipSpace()
+		if filter.op == "=~" {
+			if filter.pattern, err = p.parseRegexp(); err != nil {
+				return nil, err
+			}
+		} else if filter.literal, err = p.parseLiteral(); err != nil {
+			return nil, err
+		}
+	}
+
+	p.skipSpace()
+	if !p.consume(")") {
+		return nil, p.errorf("expected ) at end of filter")
+	}
+	return filter, nil
+}
+
+func (p *jsonPathParser) parseRegexp() (*regexp.Regexp, error) {
+	if !p.consume("/") {
+		return nil, p.errorf("expected /regexp/ after =~")
+	}
+	end := strings.Index(p.src[p.pos:], "/")
+	if end < 0 {
+		return nil, p.errorf("unterminated regexp")
+	}
+	pattern, err := regexp.Compile(p.src[p.pos : p.pos+end])
+	if err != nil {
+		return nil, p.errorf("%s", err.Error())
+	}
+	p.pos += end + 1
+	return pattern, nil
+}
+
+var jsonPathNumber = regexp.MustCompile(`^-?\d+(\.\d+)?([eE][-+]?\d+)?`)
+
+func (p *jsonPathParser) parseLiteral() (interface{}, error) {
+	switch {
+	case p.peek("'") || p.peek(`"`):
+		return p.parseQuoted()
+	case p.consume("true"):
+		return true, nil
+	case p.consume("false"):
+		return false, nil
+	case p.consume("null"):
+		return nil, nil
+	}
+
+	if num := jsonPathNumber.FindString(p.src[p.pos:]); num != "" {
+		p.pos += len(num)
+		return json.Number(num), nil
+	}
+	return nil, p.errorf("expected a string, number, boolean or null")
+}
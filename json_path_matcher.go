@@ -0,0 +1,74 @@
+package hex
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// WithJSONPath adds a matching condition against a single value within a request's JSON-encoded body, selected by a
+// JSONPath expression. The leading "$." may be omitted:
+//
+//   WithJSONPath("$.user.name", "bob")
+//   WithJSONPath("user.roles[0]", hex.R("^admin$"))
+//   WithJSONPath("$.items[*].sku", "abc-123") // passes if any item has the given sku
+//   WithJSONPath("$.items[?(@.qty > 10)].sku", hex.Any) // passes if any item has a qty greater than 10
+//   WithJSONPath("$.user", hex.P{"name": "bob"}) // values are matched the same way as WithJSONBody
+//
+// When the path selects multiple values, the condition passes if any of them match.
+// Invalid paths produce a panic.
+func (e *Expectation) WithJSONPath(path string, want interface{}) *Expectation {
+	compiled, err := compileJSONPath(path)
+	if err != nil {
+		panic(fmt.Sprintf("WithJSONPath: %s", err.Error()))
+	}
+
+	matcher, err := makeJSONMatcher(want, false)
+	if err != nil {
+		panic(fmt.Sprintf("WithJSONPath: %s", err.Error()))
+	}
+
+	return e.addMatcher(&jsonPathMatcher{
+		path:        compiled,
+		jsonMatcher: matcher,
+	})
+}
+
+type jsonPathMatcher struct {
+	path        *jsonPath
+	jsonMatcher jsonMatcher
+}
+
+var _ matcher = &jsonPathMatcher{}
+
+func (j *jsonPathMatcher) matches(req *http.Request) matchResult {
+	value, err := readJSONBody(req)
+	if err != nil {
+		return mismatch("%s", err.Error())
+	}
+
+	nodes := j.path.selectFrom(value)
+	if len(nodes) == 0 {
+		return mismatch("%s: no value found", j.path.String())
+	}
+
+	for _, node := range nodes {
+		if err := j.jsonMatcher.matchJSON(node.path, node.value); err == nil {
+			return matchSuccess
+		}
+	}
+
+	if len(nodes) == 1 {
+		return mismatch("%s", j.jsonMatcher.matchJSON(nodes[0].path, nodes[0].value).Error())
+	}
+
+	found := make([]string, len(nodes))
+	for i, node := range nodes {
+		found[i] = fmt.Sprintf("%s=%s", node.path, jsonString(node.value))
+	}
+	return mismatch("%s: no value matched %s, found %s", j.path.String(), j.jsonMatcher.String(), strings.Join(found, ", "))
+}
+
+func (j *jsonPathMatcher) String() string {
+	return fmt.Sprintf("JSON path %s matching %s", j.path.String(), j.jsonMatcher.String())
+}
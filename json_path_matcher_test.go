@@ -0,0 +1,85 @@
+package hex
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func ExampleExpectation_WithJSONPath() {
+	e := Expecter{}
+
+	e.ExpectReq("POST", "/users").WithJSONPath("$.user.roles[0]", R("^admin$"))
+	e.ExpectReq("POST", "/users").WithJSONPath("$.user.roles[*]", "user")
+
+	e.LogReq(httptest.NewRequest("POST", "/users", strings.NewReader(`{"user": {"roles": ["admin", "user"]}}`)))
+
+	fmt.Println(e.Summary())
+	// Output:
+	// Expectations
+	// 	POST /users with JSON path $.user.roles[0] matching "^admin$" - passed
+	// 	POST /users with JSON path $.user.roles[*] matching "user" - passed
+}
+
+func TestJSONPathMatcher(t *testing.T) {
+	body := `{"user": {"name": "bob", "age": 42}, "items": [{"sku": "a", "qty": 1}, {"sku": "b", "qty": 20}]}`
+
+	testCases := []struct {
+		path     string
+		want     interface{}
+		pass     bool
+		mismatch string
+	}{
+		{"$.user.name", "bob", true, ""},
+		{"user.name", R("^b"), true, ""},
+		{"$.user.age", 42, true, ""},
+		{"$.user", P{"name": "bob"}, true, ""},
+		{"$.items[?(@.qty > 10)].sku", "b", true, ""},
+		{"$.items[*].sku", "b", true, ""},
+		{"$.user.name", "sam", false, `$.user.name: expected "sam", got "bob"`},
+		{"$.user.email", Any, false, "$.user.email: no value found"},
+		{"$.items[?(@.qty > 10)].sku", "a", false, `$.items[1].sku: expected "a", got "b"`},
+		{"$.items[*].sku", "c", false, `$.items[*].sku: no value matched "c", found $.items[0].sku="a", $.items[1].sku="b"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s matching %v", tc.path, tc.want), func(t *testing.T) {
+			e := Expecter{}
+			exp := e.ExpectReq("POST", "/users").WithJSONPath(tc.path, tc.want)
+			e.LogReq(httptest.NewRequest("POST", "/users", strings.NewReader(body)))
+
+			if e.Pass() != tc.pass {
+				t.Errorf("Got %t, want %t", e.Pass(), tc.pass)
+			}
+
+			if !tc.pass {
+				if got := exp.closest.checks[2].actual; got != tc.mismatch {
+					t.Errorf("Got mismatch %q, want %q", got, tc.mismatch)
+				}
+			}
+		})
+	}
+
+	t.Run("It composes with other matchers", func(t *testing.T) {
+		e := Expecter{}
+		e.ExpectReq("POST", "/users").
+			WithQuery("dry_run", "1").
+			WithJSONPath("user.name", "bob").
+			WithJSONPath("user.age", 42)
+
+		e.LogReq(httptest.NewRequest("POST", "/users", strings.NewReader(body)))
+		e.LogReq(httptest.NewRequest("POST", "/users?dry_run=1", strings.NewReader(body)))
+		assertPassed(t, 1, &e)
+	})
+
+	t.Run("Invalid paths panic", func(t *testing.T) {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Errorf("Expected WithJSONPath with an invalid path to panic")
+			}
+		}()
+		e := Expecter{}
+		e.ExpectReq("POST", "/users").WithJSONPath("$[", Any)
+	})
+}
@@ -0,0 +1,83 @@
+package hex
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	doc := `{
+		"user": {"name": "bob", "roles": ["admin", "user"], "first name": "Bob"},
+		"items": [
+			{"sku": "a", "qty": 5, "tags": ["x"]},
+			{"sku": "b", "qty": 15, "gift": true},
+			{"sku": "c", "qty": 25, "note": null}
+		]
+	}`
+
+	testCases := []struct {
+		path string
+		want []string
+	}{
+		{"$", []string{"$"}},
+		{"$.user.name", []string{"$.user.name"}},
+		{"user.name", []string{"$.user.name"}},
+		{"$['user']['name']", []string{"$.user.name"}},
+		{`$["user"]["first name"]`, []string{`$.user["first name"]`}},
+		{"$.user.roles[0]", []string{"$.user.roles[0]"}},
+		{"user.roles[-1]", []string{"$.user.roles[1]"}},
+		{"$.user.roles[2]", nil},
+		{"$.user.missing", nil},
+		{"$.user.name.missing", nil},
+		{"$.user.roles[*]", []string{"$.user.roles[0]", "$.user.roles[1]"}},
+		{"$.items[*].sku", []string{"$.items[0].sku", "$.items[1].sku", "$.items[2].sku"}},
+		{"$.user.*", []string{`$.user["first name"]`, "$.user.name", "$.user.roles"}},
+		{"$..sku", []string{"$.items[0].sku", "$.items[1].sku", "$.items[2].sku"}},
+		{"$.items[?(@.qty > 10)].sku", []string{"$.items[1].sku", "$.items[2].sku"}},
+		{"$.items[?(@.qty >= 15)].sku", []string{"$.items[1].sku", "$.items[2].sku"}},
+		{"$.items[?(@.qty < 15)].sku", []string{"$.items[0].sku"}},
+		{"$.items[?(@.qty <= 15)].sku", []string{"$.items[0].sku", "$.items[1].sku"}},
+		{"$.items[?(@.qty == 15)].sku", []string{"$.items[1].sku"}},
+		{"$.items[?(@.sku == 'c')].qty", []string{"$.items[2].qty"}},
+		{`$.items[?(@.sku != "c")].qty`, []string{"$.items[0].qty", "$.items[1].qty"}},
+		{"$.items[?(@.sku =~ /^[ab]$/)].qty", []string{"$.items[0].qty", "$.items[1].qty"}},
+		{"$.items[?(@.gift == true)].sku", []string{"$.items[1].sku"}},
+		{"$.items[?(@.note == null)].sku", []string{"$.items[2].sku"}},
+		{"$.items[?(@.gift)].sku", []string{"$.items[1].sku"}},
+		{"$.items[?(@.tags[0] == 'x')].sku", []string{"$.items[0].sku"}},
+	}
+
+	value, err := decodeJSON([]byte(doc))
+	if err != nil {
+		t.Fatalf("Invalid JSON document: %s", err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			path, err := compileJSONPath(tc.path)
+			if err != nil {
+				t.Fatalf("Unexpected error compiling %s: %s", tc.path, err)
+			}
+
+			var got []string
+			for _, node := range path.selectFrom(value) {
+				got = append(got, node.path)
+			}
+
+			if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+				t.Errorf("Got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJSONPathInvalid(t *testing.T) {
+	for _, path := range []string{"", "$.", "$[", "$[abc]", "$.items[?(@.qty > )]", "$.items[?(qty > 1)]", "$['name"} {
+		t.Run(fmt.Sprintf("%q", path), func(t *testing.T) {
+			if _, err := compileJSONPath(path); err == nil {
+				t.Errorf("Expected compileJSONPath(%q) to return an error", path)
+			}
+		})
+	}
+}
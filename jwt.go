@@ -0,0 +1,221 @@
+package hex
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	// Register the hash functions used by JWT signing algorithms
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// WithJWTClaims matches requests with a bearer token that is a JWT, whose claims match want. Claims are matched like
+// the body given to WithJSONBody, and the token's signature is not verified:
+//
+//   WithJWTClaims(hex.P{"sub": "user-123", "scope": hex.R(`\badmin\b`)})
+//
+// Use WithVerifiedJWTClaims to additionally verify the token's signature.
+func (e *Expectation) WithJWTClaims(want interface{}) *Expectation {
+	m, err := makeJSONMatcher(want, false)
+	if err != nil {
+		panic(fmt.Sprintf("WithJWTClaims: %s", err.Error()))
+	}
+	return e.addMatcher(&jwtClaimsMatcher{claims: m})
+}
+
+// WithVerifiedJWTClaims is like WithJWTClaims, but additionally requires the token's signature to be valid for key.
+// The key must suit the token's algorithm:
+//
+//   - A []byte or string secret, for HS256, HS384 and HS512
+//   - An *rsa.PublicKey, for RS256, RS384, RS512, PS256, PS384 and PS512
+//   - An *ecdsa.PublicKey, for ES256, ES384 and ES512
+//
+// Only the signature is verified. Time-based claims like exp and nbf can be matched like any other claim.
+func (e *Expectation) WithVerifiedJWTClaims(key interface{}, want interface{}) *Expectation {
+	switch key.(type) {
+	case []byte, string, *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		panic(fmt.Sprintf("WithVerifiedJWTClaims: cannot verify signatures with key of type %T", key))
+	}
+
+	m, err := makeJSONMatcher(want, false)
+	if err != nil {
+		panic(fmt.Sprintf("WithVerifiedJWTClaims: %s", err.Error()))
+	}
+	return e.addMatcher(&jwtClaimsMatcher{claims: m, key: key})
+}
+
+type jwtClaimsMatcher struct {
+	claims jsonMatcher
+
+	// key verifies the token's signature, if non-nil
+	key interface{}
+}
+
+var _ matcher = &jwtClaimsMatcher{}
+
+func (j *jwtClaimsMatcher) matches(req *http.Request) matchResult {
+	token, result := bearerToken(req)
+	if !result.ok {
+		return result
+	}
+
+	jwt, err := parseJWT(token)
+	if err != nil {
+		return mismatch("got invalid JWT: %s", err.Error())
+	}
+
+	if j.key != nil {
+		if err := jwt.verify(j.key); err != nil {
+			return mismatch("got JWT with invalid signature: %s", err.Error())
+		}
+	}
+
+	if err := j.claims.matchJSON("$", jwt.claims); err != nil {
+		return mismatch("%s", err.Error())
+	}
+	return matchSuccess
+}
+
+func (j *jwtClaimsMatcher) String() string {
+	if j.key != nil {
+		return fmt.Sprintf("verified JWT claims matching %s", j.claims.String())
+	}
+	return fmt.Sprintf("JWT claims matching %s", j.claims.String())
+}
+
+type jwt struct {
+	alg       string
+	claims    interface{}
+	signed    string
+	signature []byte
+}
+
+// parseJWT decodes a JWT in compact serialization, without verifying it
+func parseJWT(token string) (*jwt, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("expected 3 segments, got %d", len(parts))
+	}
+
+	headerJSON, err := decodeJWTSegment(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("header: %s", err.Error())
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("header: %s", err.Error())
+	}
+
+	claimsJSON, err := decodeJWTSegment(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("claims: %s", err.Error())
+	}
+	claims, err := decodeJSON(claimsJSON)
+	if err != nil {
+		return nil, fmt.Errorf("claims: %s", err.Error())
+	}
+
+	signature, err := decodeJWTSegment(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("signature: %s", err.Error())
+	}
+
+	return &jwt{
+		alg:       header.Alg,
+		claims:    claims,
+		signed:    parts[0] + "." + parts[1],
+		signature: signature,
+	}, nil
+}
+
+// decodeJWTSegment decodes unpadded base64url, tolerating padding added by non-conforming encoders
+func decodeJWTSegment(segment string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+}
+
+var jwtHashes = map[string]crypto.Hash{
+	"256": crypto.SHA256,
+	"384": crypto.SHA384,
+	"512": crypto.SHA512,
+}
+
+// verify checks the token's signature using key
+func (t *jwt) verify(key interface{}) error {
+	if len(t.alg) != 5 {
+		return fmt.Errorf("unsupported algorithm %q", t.alg)
+	}
+	hash, ok := jwtHashes[t.alg[2:]]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm %q", t.alg)
+	}
+
+	h := hash.New()
+	h.Write([]byte(t.signed))
+	digest := h.Sum(nil)
+
+	family := t.alg[:2]
+	switch family {
+	case "HS":
+		var secret []byte
+		switch k := key.(type) {
+		case []byte:
+			secret = k
+		case string:
+			secret = []byte(k)
+		default:
+			return fmt.Errorf("algorithm %s cannot be verified with key of type %T", t.alg, key)
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write([]byte(t.signed))
+		if !hmac.Equal(mac.Sum(nil), t.signature) {
+			return fmt.Errorf("signature does not match")
+		}
+		return nil
+
+	case "RS", "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s cannot be verified with key of type %T", t.alg, key)
+		}
+		var err error
+		if family == "RS" {
+			err = rsa.VerifyPKCS1v15(pub, hash, digest, t.signature)
+		} else {
+			err = rsa.VerifyPSS(pub, hash, digest, t.signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		if err != nil {
+			return fmt.Errorf("signature does not match")
+		}
+		return nil
+
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("algorithm %s cannot be verified with key of type %T", t.alg, key)
+		}
+		// ECDSA signatures are the concatenation of r and s, each padded to the size of the curve
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(t.signature) != 2*size {
+			return fmt.Errorf("signature does not match")
+		}
+		r := new(big.Int).SetBytes(t.signature[:size])
+		s := new(big.Int).SetBytes(t.signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return fmt.Errorf("signature does not match")
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unsupported algorithm %q", t.alg)
+}
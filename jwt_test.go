@@ -0,0 +1,155 @@
+package hex
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// signJWT builds a JWT with the given algorithm and claims. key is a []byte, *rsa.PrivateKey or *ecdsa.PrivateKey.
+func signJWT(t *testing.T, alg string, claims P, key interface{}) string {
+	t.Helper()
+
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	body, err := json.Marshal(jsonClaims(claims))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+
+	hash := jwtHashes[alg[2:]]
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(hash.New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		if strings.HasPrefix(alg, "PS") {
+			signature, err = rsa.SignPSS(rand.Reader, k, hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			signature, err = rsa.SignPKCS1v15(rand.Reader, k, hash, digest)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, signErr := ecdsa.Sign(rand.Reader, k, digest)
+		size := (k.Curve.Params().BitSize + 7) / 8
+		signature = make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+		err = signErr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func jsonClaims(claims P) map[string]interface{} {
+	m := map[string]interface{}{}
+	for k, v := range claims {
+		m[k.(string)] = v
+	}
+	return m
+}
+
+func TestJWTClaims(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherRSAKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := P{"sub": "user-123", "admin": true, "exp": 1700000000}
+
+	testCases := []struct {
+		name   string
+		token  string
+		key    interface{}
+		want   interface{}
+		actual string
+	}{
+		{"unverified", signJWT(t, "HS256", claims, []byte("other")), nil, P{"sub": "user-123"}, ""},
+		{"unverified nested matchers", signJWT(t, "HS256", claims, secret), nil, P{"sub": R("^user-"), "exp": 1700000000, "admin": true}, ""},
+		{"unverified mismatch", signJWT(t, "HS256", claims, secret), nil, P{"sub": "user-456"}, `$.sub: expected "user-456", got "user-123"`},
+		{"HS256", signJWT(t, "HS256", claims, secret), secret, P{"sub": "user-123"}, ""},
+		{"HS384 string key", signJWT(t, "HS384", claims, secret), "secret", P{"sub": "user-123"}, ""},
+		{"HS512", signJWT(t, "HS512", claims, secret), secret, P{"sub": "user-123"}, ""},
+		{"HS256 wrong secret", signJWT(t, "HS256", claims, []byte("other")), secret, P{"sub": "user-123"}, "got JWT with invalid signature: signature does not match"},
+		{"RS256", signJWT(t, "RS256", claims, rsaKey), &rsaKey.PublicKey, P{"sub": "user-123"}, ""},
+		{"RS512", signJWT(t, "RS512", claims, rsaKey), &rsaKey.PublicKey, P{"sub": "user-123"}, ""},
+		{"PS256", signJWT(t, "PS256", claims, rsaKey), &rsaKey.PublicKey, P{"sub": "user-123"}, ""},
+		{"RS256 wrong key", signJWT(t, "RS256", claims, rsaKey), &otherRSAKey.PublicKey, P{"sub": "user-123"}, "got JWT with invalid signature: signature does not match"},
+		{"ES256", signJWT(t, "ES256", claims, ecKey), &ecKey.PublicKey, P{"sub": "user-123"}, ""},
+		{"ES384", signJWT(t, "ES384", claims, ec384Key), &ec384Key.PublicKey, P{"sub": "user-123"}, ""},
+		{"ES256 wrong key", signJWT(t, "ES256", claims, ecKey), &ec384Key.PublicKey, P{"sub": "user-123"}, "got JWT with invalid signature: signature does not match"},
+		{"algorithm and key mismatch", signJWT(t, "HS256", claims, secret), &rsaKey.PublicKey, P{}, "got JWT with invalid signature: algorithm HS256 cannot be verified with key of type *rsa.PublicKey"},
+		{"none algorithm", "eyJhbGciOiJub25lIn0.eyJzdWIiOiJ1c2VyLTEyMyJ9.", secret, P{}, `got JWT with invalid signature: unsupported algorithm "none"`},
+		{"malformed", "abc.def", nil, P{}, "got invalid JWT: expected 3 segments, got 2"},
+		{"invalid claims", "eyJhbGciOiJub25lIn0.bm90IGpzb24.", nil, P{}, "got invalid JWT: claims: invalid character 'o' in literal null (expecting 'u')"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := Expecter{}
+			exp := e.ExpectReq("GET", "/")
+			if tc.key == nil {
+				exp.WithJWTClaims(tc.want)
+			} else {
+				exp.WithVerifiedJWTClaims(tc.key, tc.want)
+			}
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			e.LogReq(req)
+
+			if tc.actual == "" {
+				if !e.Pass() {
+					t.Errorf("Expected to pass\n%s", e.Summary())
+				}
+				return
+			}
+			if exp.closest == nil {
+				t.Fatalf("Expected to fail\n%s", e.Summary())
+			}
+			if got := exp.closest.checks[2].actual; got != tc.actual {
+				t.Errorf("Got %q, want %q", got, tc.actual)
+			}
+		})
+	}
+}
+
+func TestWithVerifiedJWTClaimsInvalidKey(t *testing.T) {
+	defer func() {
+		want := "WithVerifiedJWTClaims: cannot verify signatures with key of type int"
+		if got := fmt.Sprint(recover()); got != want {
+			t.Errorf("Got panic %q, want %q", got, want)
+		}
+	}()
+	e := Expecter{}
+	e.ExpectReq("GET", "/").WithVerifiedJWTClaims(123, P{})
+}
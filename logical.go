@@ -0,0 +1,279 @@
+package hex
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// LogicalMatcher negates or combines matchers. It's created by AnyOf, AllOf and Not, and can be used:
+//
+//   - Anywhere strings are matched, ie ExpectReq's method and path, or the keys and values given to WithQuery,
+//     in which case each argument is a string, regular expression, or anything else that matches strings
+//   - As the single argument to WithQuery, WithHeader or WithBody, in which case each argument may be anything those
+//     functions accept as a single argument, such as a key or a P
+//   - As the single argument to ExpectReq, or the argument to Matching, in which case each argument is a MatchFn or a
+//     P of the kind accepted by ExpectReq
+//
+// For example:
+//
+//   ExpectReq("GET", hex.AnyOf("/a", "/b"))                          // path is /a or /b
+//   WithHeader(hex.Not("X-Debug"))                                   // header X-Debug is not present
+//   WithQuery("sort", hex.Not(hex.AnyOf("id", "name")))              // sort is present, but not "id" or "name"
+//   WithQuery(hex.AnyOf(hex.P{"q": "cats"}, hex.P{"tag": "cats"}))   // ?q=cats or ?tag=cats
+//   Matching(hex.Not(hex.P{"method": "GET", "path": "/health"}))     // any request but GET /health
+//
+// Combinators can also be used as keys in a P, ie P{hex.AnyOf("q", "query"): "cats"}. The zero LogicalMatcher matches
+// everything.
+type LogicalMatcher struct {
+	// The combination is held by pointer, so that a LogicalMatcher is hashable and can be used as a key in a P
+	*logicalCombination
+}
+
+type logicalCombination struct {
+	op   logicalOp
+	args []interface{}
+}
+
+// combination returns the matcher's operator and arguments. The zero LogicalMatcher combines nothing, and like AllOf
+// with no arguments, it matches everything.
+func (l LogicalMatcher) combination() *logicalCombination {
+	if l.logicalCombination == nil {
+		return &logicalCombination{op: opAllOf}
+	}
+	return l.logicalCombination
+}
+
+type logicalOp int
+
+const (
+	opAnyOf logicalOp = 1
+	opAllOf logicalOp = 2
+	opNot   logicalOp = 3
+)
+
+// AnyOf matches if at least one of its arguments matches
+func AnyOf(args ...interface{}) LogicalMatcher {
+	return LogicalMatcher{&logicalCombination{op: opAnyOf, args: args}}
+}
+
+// AllOf matches if every one of its arguments matches
+func AllOf(args ...interface{}) LogicalMatcher {
+	return LogicalMatcher{&logicalCombination{op: opAllOf, args: args}}
+}
+
+// Not matches if its argument does not match
+func Not(arg interface{}) LogicalMatcher {
+	return LogicalMatcher{&logicalCombination{op: opNot, args: []interface{}{arg}}}
+}
+
+// String describes the matcher, ie `any of (q, tag)`
+func (l LogicalMatcher) String() string {
+	c := l.combination()
+	parts := make([]string, len(c.args))
+	for i, arg := range c.args {
+		parts[i] = fmt.Sprintf("%v", arg)
+	}
+	return describeLogical(c.op, parts)
+}
+
+func describeLogical(op logicalOp, parts []string) string {
+	switch op {
+	case opAnyOf:
+		return fmt.Sprintf("any of (%s)", strings.Join(parts, ", "))
+	case opAllOf:
+		return fmt.Sprintf("all of (%s)", strings.Join(parts, ", "))
+	}
+	return fmt.Sprintf("not (%s)", strings.Join(parts, ", "))
+}
+
+// String matching
+
+type logicalStringMatcher struct {
+	op       logicalOp
+	matchers []stringMatcher
+}
+
+var _ stringMatcher = &logicalStringMatcher{}
+
+func makeLogicalStringMatcher(l LogicalMatcher) (stringMatcher, error) {
+	c := l.combination()
+	m := &logicalStringMatcher{op: c.op}
+	for _, arg := range c.args {
+		sm, err := makeStringMatcher(arg)
+		if err != nil {
+			return nil, err
+		}
+		m.matchers = append(m.matchers, sm)
+	}
+	return m, nil
+}
+
+func (l *logicalStringMatcher) match(candidate string) bool {
+	switch l.op {
+	case opAnyOf:
+		for _, m := range l.matchers {
+			if m.match(candidate) {
+				return true
+			}
+		}
+		return false
+	case opAllOf:
+		for _, m := range l.matchers {
+			if !m.match(candidate) {
+				return false
+			}
+		}
+		return true
+	}
+	return !l.matchers[0].match(candidate)
+}
+
+func (l *logicalStringMatcher) String() string {
+	parts := make([]string, len(l.matchers))
+	for i, m := range l.matchers {
+		parts[i] = m.String()
+	}
+	return describeLogical(l.op, parts)
+}
+
+// Request matching
+
+// Matching adds a condition on the whole request, which may be a MatchFn, a P of the kind accepted by ExpectReq, or a
+// LogicalMatcher combining them:
+//
+//   server.ExpectReq(hex.Any, hex.Any).Matching(hex.Not(hex.P{"header": "X-Debug"}))
+func (e *Expectation) Matching(arg interface{}) *Expectation {
+	m, err := makeRequestMatcher(arg)
+	if err != nil {
+		panic(fmt.Sprintf("Matching: %s", err.Error()))
+	}
+	return e.addMatcher(m)
+}
+
+// makeRequestMatcher builds a matcher from a MatchFn, a P or a LogicalMatcher
+func makeRequestMatcher(arg interface{}) (matcher, error) {
+	if fn, ok := asMatchFn(arg); ok {
+		return &withMatcher{fn: fn}, nil
+	}
+
+	if params, ok := asParams(arg); ok {
+		method, path, matchers, err := parseExpectReqParams(params)
+		if err != nil {
+			return nil, err
+		}
+		m := &requestParamsMatcher{matchers: matchers}
+		if _, ok := params["method"]; ok {
+			m.method = method
+		}
+		if _, ok := params["path"]; ok {
+			m.path = path
+		}
+		return m, nil
+	}
+
+	if l, ok := arg.(LogicalMatcher); ok {
+		c := l.combination()
+		m := &logicalRequestMatcher{op: c.op}
+		for _, arg := range c.args {
+			rm, err := makeRequestMatcher(arg)
+			if err != nil {
+				return nil, err
+			}
+			m.matchers = append(m.matchers, rm)
+		}
+		return m, nil
+	}
+
+	return nil, fmt.Errorf("Cannot use value %v when matching against requests", arg)
+}
+
+// requestParamsMatcher matches a request against a P of the kind accepted by ExpectReq
+type requestParamsMatcher struct {
+	method, path stringMatcher
+	matchers     []matcher
+}
+
+var _ matcher = &requestParamsMatcher{}
+
+func (r *requestParamsMatcher) matches(req *http.Request) matchResult {
+	if r.method != nil {
+		if result := matchString(r.method, req.Method); !result.ok {
+			return mismatch("method: %s", result.actual)
+		}
+	}
+	if r.path != nil {
+		if result := matchString(r.path, req.URL.Path); !result.ok {
+			return mismatch("path: %s", result.actual)
+		}
+	}
+	for _, m := range r.matchers {
+		rewindBody(req)
+		if result := m.matches(req); !result.ok {
+			return mismatch("%s: %s", m.String(), result.actual)
+		}
+	}
+	return matchSuccess
+}
+
+func (r *requestParamsMatcher) String() string {
+	var parts []string
+	if r.method != nil {
+		parts = append(parts, "method "+r.method.String())
+	}
+	if r.path != nil {
+		parts = append(parts, "path "+r.path.String())
+	}
+	for _, m := range r.matchers {
+		parts = append(parts, m.String())
+	}
+	if len(parts) == 0 {
+		return "any request"
+	}
+	return strings.Join(parts, " and ")
+}
+
+type logicalRequestMatcher struct {
+	op       logicalOp
+	matchers []matcher
+}
+
+var _ matcher = &logicalRequestMatcher{}
+
+func (l *logicalRequestMatcher) matches(req *http.Request) matchResult {
+	switch l.op {
+	case opAnyOf:
+		actuals := make([]string, 0, len(l.matchers))
+		for _, m := range l.matchers {
+			rewindBody(req)
+			result := m.matches(req)
+			if result.ok {
+				return matchSuccess
+			}
+			actuals = append(actuals, result.actual)
+		}
+		return mismatch("%s", strings.Join(actuals, "; "))
+	case opAllOf:
+		for _, m := range l.matchers {
+			rewindBody(req)
+			if result := m.matches(req); !result.ok {
+				return result
+			}
+		}
+		return matchSuccess
+	}
+
+	rewindBody(req)
+	if l.matchers[0].matches(req).ok {
+		return mismatch("matched %s", l.matchers[0].String())
+	}
+	return matchSuccess
+}
+
+func (l *logicalRequestMatcher) String() string {
+	parts := make([]string, len(l.matchers))
+	for i, m := range l.matchers {
+		parts[i] = m.String()
+	}
+	return describeLogical(l.op, parts)
+}
@@ -0,0 +1,195 @@
+package hex
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func ExampleNot() {
+	e := Expecter{}
+
+	e.ExpectReq("GET", AnyOf("/users", "/people")).WithHeader(Not("X-Debug"))
+
+	req := httptest.NewRequest("GET", "/people", nil)
+	req.Header.Set("X-Debug", "1")
+	e.LogReq(req)
+
+	fmt.Println(e.Summary())
+	// Output:
+	// Expectations
+	// 	GET any of (/users, /people) with header matching not (X-Debug) - failed, no matching requests
+	// 		closest unmatched request: GET /people
+	// 			method GET: passed
+	// 			path any of (/users, /people): passed
+	// 			header matching not (X-Debug): failed, got X-Debug=["1"]
+	// Unmatched Requests
+	// 	GET /people
+}
+
+func TestLogicalStringMatcher(t *testing.T) {
+	testCases := []struct {
+		arg   interface{}
+		input string
+		want  bool
+		desc  string
+	}{
+		{AnyOf("a", "b"), "a", true, "any of (a, b)"},
+		{AnyOf("a", "b"), "b", true, "any of (a, b)"},
+		{AnyOf("a", "b"), "c", false, "any of (a, b)"},
+		{AnyOf(), "a", false, "any of ()"},
+		{AllOf(R("^a"), R("z$")), "abcz", true, "all of (^a, z$)"},
+		{AllOf(R("^a"), R("z$")), "abc", false, "all of (^a, z$)"},
+		{AllOf(), "a", true, "all of ()"},
+		{LogicalMatcher{}, "a", true, "all of ()"},
+		{Not("a"), "a", false, "not (a)"},
+		{Not("a"), "b", true, "not (a)"},
+		{Not(AnyOf("a", R("^b"))), "bc", false, "not (any of (a, ^b))"},
+		{Not(AnyOf("a", R("^b"))), "cb", true, "not (any of (a, ^b))"},
+		{Not(None), "a", true, "not (<none>)"},
+		{AllOf(Path("/users/{id}"), Not("/users/me")), "/users/1", true, "all of (/users/{id}, not (/users/me))"},
+		{AllOf(Path("/users/{id}"), Not("/users/me")), "/users/me", false, "all of (/users/{id}, not (/users/me))"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%v %s", tc.arg, tc.input), func(t *testing.T) {
+			m, err := makeStringMatcher(tc.arg)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := m.match(tc.input); got != tc.want {
+				t.Errorf("match: got %t, want %t", got, tc.want)
+			}
+			if got := m.String(); got != tc.desc {
+				t.Errorf("String: got %q, want %q", got, tc.desc)
+			}
+		})
+	}
+
+	if _, err := makeStringMatcher(AnyOf("a", 123)); err == nil {
+		t.Errorf("Expected an error for an invalid argument")
+	}
+}
+
+func TestLogicalValuesMatcher(t *testing.T) {
+	testCases := []struct {
+		args  []interface{}
+		query string
+		want  bool
+	}{
+		// A top-level Not negates the presence of a key
+		{[]interface{}{Not("debug")}, "q=x", true},
+		{[]interface{}{Not("debug")}, "", true},
+		{[]interface{}{Not("debug")}, "q=x&debug=1", false},
+		{[]interface{}{Not(P{"debug": "1"})}, "debug=0", true},
+		{[]interface{}{Not(P{"debug": "1"})}, "debug=1", false},
+
+		// Not as a value matches keys with some other value
+		{[]interface{}{"sort", Not(AnyOf("id", "name"))}, "sort=date", true},
+		{[]interface{}{"sort", Not(AnyOf("id", "name"))}, "sort=id", false},
+		{[]interface{}{"sort", Not(AnyOf("id", "name"))}, "", false},
+
+		{[]interface{}{AllOf("q", Not("debug"))}, "q=x", true},
+		{[]interface{}{AllOf("q", Not("debug"))}, "q=x&debug=1", false},
+		{[]interface{}{AllOf("q", Not("debug"))}, "page=1", false},
+		{[]interface{}{AnyOf(P{"q": "x"}, AllOf("a", "b"))}, "a=1&b=2", true},
+		{[]interface{}{AnyOf(P{"q": "x"}, AllOf("a", "b"))}, "a=1", false},
+
+		// Combinators can be used as keys
+		{[]interface{}{AnyOf("a", "b"), "1"}, "b=1", true},
+		{[]interface{}{AnyOf("a", "b"), "1"}, "b=2", false},
+		{[]interface{}{P{AnyOf("q", "query"): "cats"}}, "query=cats", true},
+		{[]interface{}{P{AnyOf("q", "query"): "cats"}}, "q=dogs", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%v %s", tc.args, tc.query), func(t *testing.T) {
+			values, err := url.ParseQuery(tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, err := makeURLValuesMatcher(tc.args)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := u.matches(values); got != tc.want {
+				t.Errorf("Got %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLogicalRequestMatcher(t *testing.T) {
+	isPost := MatchFn(func(req *http.Request) bool { return req.Method == "POST" })
+
+	testCases := []struct {
+		arg    interface{}
+		req    *http.Request
+		actual string
+		desc   string
+	}{
+		{Not(P{"method": "GET", "path": "/health"}), mockGet("/users"), "", "not (method GET and path /health)"},
+		{Not(P{"method": "GET", "path": "/health"}), mockGet("/health"), "matched method GET and path /health", "not (method GET and path /health)"},
+		{AnyOf(P{"path": "/a"}, P{"path": "/b"}), mockGet("/b"), "", "any of (path /a, path /b)"},
+		{AnyOf(P{"path": "/a"}, P{"path": "/b"}), mockGet("/c"), `path: got "/c"; path: got "/c"`, "any of (path /a, path /b)"},
+		{AllOf(isPost, P{"query": "draft"}), mockPost("/posts?draft", nil), "", "all of (<custom With matcher>, query string matching draft)"},
+		{AllOf(isPost, P{"query": "draft"}), mockPost("/posts", nil), "query string matching draft: got none", "all of (<custom With matcher>, query string matching draft)"},
+		{AllOf(isPost, P{"query": "draft"}), mockGet("/posts?draft"), "custom matcher returned false", "all of (<custom With matcher>, query string matching draft)"},
+		{P{}, mockGet("/"), "", "any request"},
+		{LogicalMatcher{}, mockGet("/"), "", "all of ()"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%v %s %s", tc.arg, tc.req.Method, tc.req.URL), func(t *testing.T) {
+			m, err := makeRequestMatcher(tc.arg)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			result := m.matches(tc.req)
+			if result.ok != (tc.actual == "") || result.actual != tc.actual {
+				t.Errorf("Got %+v, want actual %q", result, tc.actual)
+			}
+			if got := m.String(); got != tc.desc {
+				t.Errorf("String: got %q, want %q", got, tc.desc)
+			}
+		})
+	}
+}
+
+func TestMatching(t *testing.T) {
+	t.Run("Matching adds a request-level condition", func(t *testing.T) {
+		e := Expecter{}
+		e.ExpectReq(Any, Any).Matching(Not(P{"header": "X-Debug"}))
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Debug", "1")
+		e.LogReq(req)
+
+		if e.Pass() {
+			t.Errorf("Expected request with X-Debug header not to match")
+		}
+	})
+
+	t.Run("ExpectReq accepts a LogicalMatcher", func(t *testing.T) {
+		e := Expecter{}
+		e.ExpectReq(AnyOf(P{"method": "GET", "path": "/a"}, P{"method": "POST"}))
+		e.LogReq(mockPost("/b", nil))
+
+		if !e.Pass() {
+			t.Errorf("Expected POST /b to match\n%s", e.Summary())
+		}
+	})
+
+	t.Run("Invalid arguments panic", func(t *testing.T) {
+		defer func() {
+			want := "Matching: Cannot use value 123 when matching against requests"
+			if got := fmt.Sprint(recover()); got != want {
+				t.Errorf("Got panic %q, want %q", got, want)
+			}
+		}()
+		e := Expecter{}
+		e.ExpectReq("GET", "/").Matching(AnyOf(123))
+	})
+}
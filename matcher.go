@@ -6,10 +6,26 @@ import (
 )
 
 type matcher interface {
-	matches(req *http.Request) bool
+	matches(req *http.Request) matchResult
 	String() string
 }
 
+// matchResult is the outcome of testing a request against a matcher
+type matchResult struct {
+	ok bool
+
+	// actual describes what was found in the request when it failed to match, ie `got "sam"`
+	actual string
+}
+
+// matchSuccess is the result of a successful match
+var matchSuccess = matchResult{ok: true}
+
+// mismatch returns a failed matchResult, describing what was actually found in the request
+func mismatch(format string, args ...interface{}) matchResult {
+	return matchResult{actual: fmt.Sprintf(format, args...)}
+}
+
 func matcherArgsToString(args []interface{}) string {
 	if len(args) == 0 {
 		return "<no args>"
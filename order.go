@@ -0,0 +1,64 @@
+package hex
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// After requires that the expectation is not matched by any request until other has been matched at least once.
+// A request that matches the expectation too early still counts as a match, but the expectation fails with an ordering
+// violation, and the summary shows the order in which ordered expectations were actually matched.
+func (e *Expectation) After(other *Expectation) *Expectation {
+	if other == e {
+		panic("An expectation cannot be ordered after itself")
+	}
+	if other.expecter != e.expecter {
+		panic("After called with an expectation belonging to a different Expecter")
+	}
+
+	defer e.lock()()
+	e.after = append(e.after, other)
+	e.ordered = true
+	other.ordered = true
+	return e
+}
+
+// InOrder requires that the given expectations are first matched in the order given. For example, to assert that a
+// client logs in, fetches a profile and then logs out:
+//
+//   login := server.ExpectReq("POST", "/session")
+//   profile := server.ExpectReq("GET", "/profile")
+//   logout := server.ExpectReq("DELETE", "/session")
+//   server.InOrder(login, profile, logout)
+//
+// It's equivalent to calling After on each expectation with the one before it.
+func (e *Expecter) InOrder(exps ...*Expectation) {
+	for i := 1; i < len(exps); i++ {
+		exps[i].After(exps[i-1])
+	}
+}
+
+// checkOrder records the request as part of the observed order, and records a violation if any expectation that
+// must be matched first has not been. The Expecter's lock must be held.
+func (e *Expectation) checkOrder(req *http.Request) {
+	if n := len(e.expecter.ordered); n == 0 || e.expecter.ordered[n-1] != req {
+		e.expecter.ordered = append(e.expecter.ordered, req)
+	}
+
+	for _, prereq := range e.after {
+		if len(prereq.matches) == 0 {
+			e.violations = append(e.violations, fmt.Sprintf("ordering violation, matched before %s", prereq.name()))
+		}
+	}
+}
+
+// observedOrder describes the requests that matched ordered expectations, in the order they arrived.
+// The Expecter's lock must be held.
+func (e *Expecter) observedOrder() string {
+	reqs := make([]string, len(e.ordered))
+	for i, req := range e.ordered {
+		reqs[i] = fmt.Sprintf("%s %s", req.Method, req.URL.Path)
+	}
+	return strings.Join(reqs, ", ")
+}
@@ -0,0 +1,128 @@
+package hex
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+)
+
+func ExampleExpecter_InOrder() {
+	e := Expecter{}
+
+	login := e.ExpectReq("POST", "/session")
+	profile := e.ExpectReq("GET", "/profile")
+	logout := e.ExpectReq("DELETE", "/session")
+	e.InOrder(login, profile, logout)
+
+	e.LogReq(httptest.NewRequest("GET", "/profile", nil))
+	e.LogReq(httptest.NewRequest("POST", "/session", nil))
+	e.LogReq(httptest.NewRequest("DELETE", "/session", nil))
+
+	fmt.Println(e.Summary())
+	// Output:
+	// Expectations
+	// 	POST /session - passed
+	// 	DELETE /session - passed
+	// 	GET /profile - failed, ordering violation, matched before POST /session (observed order: GET /profile, POST /session, DELETE /session)
+}
+
+func TestOrder(t *testing.T) {
+	t.Run("Expectations matched in order pass", func(t *testing.T) {
+		e := Expecter{}
+		a := e.ExpectReq("POST", "/a")
+		b := e.ExpectReq("GET", "/b")
+		c := e.ExpectReq("DELETE", "/c")
+		e.InOrder(a, b, c)
+
+		e.LogReq(httptest.NewRequest("POST", "/a", nil))
+		e.LogReq(httptest.NewRequest("GET", "/b", nil))
+		e.LogReq(httptest.NewRequest("GET", "/b", nil))
+		e.LogReq(httptest.NewRequest("DELETE", "/c", nil))
+		assertPassed(t, 3, &e)
+	})
+
+	t.Run("Unordered requests in between ordered ones don't matter", func(t *testing.T) {
+		e := Expecter{}
+		a := e.ExpectReq("POST", "/a")
+		b := e.ExpectReq("GET", "/b")
+		e.ExpectReq("GET", "/status")
+		b.After(a)
+
+		e.LogReq(httptest.NewRequest("GET", "/status", nil))
+		e.LogReq(httptest.NewRequest("POST", "/a", nil))
+		e.LogReq(httptest.NewRequest("GET", "/status", nil))
+		e.LogReq(httptest.NewRequest("GET", "/b", nil))
+		assertPassed(t, 3, &e)
+	})
+
+	t.Run("A request matched before its prerequisite is a violation", func(t *testing.T) {
+		e := Expecter{}
+		a := e.ExpectReq("POST", "/a")
+		b := e.ExpectReq("GET", "/b").After(a)
+
+		e.LogReq(httptest.NewRequest("GET", "/b", nil))
+		e.LogReq(httptest.NewRequest("POST", "/a", nil))
+		e.LogReq(httptest.NewRequest("GET", "/b", nil))
+		assertPassedFailed(t, 1, 1, &e)
+
+		want := "ordering violation, matched before POST /a (observed order: GET /b, POST /a, GET /b)"
+		if got := b.failureReason(); got != want {
+			t.Errorf("failureReason: got %q, want %q", got, want)
+		}
+
+		if len(e.UnmatchedRequests()) != 0 {
+			t.Errorf("Expected out-of-order requests to still count as matched")
+		}
+	})
+
+	t.Run("A request matching both an expectation and its prerequisite is not a violation", func(t *testing.T) {
+		e := Expecter{}
+		a := e.ExpectReq("GET", "/users")
+		b := e.ExpectReq("GET", "/users").WithQuery("page", "1").After(a)
+
+		e.LogReq(httptest.NewRequest("GET", "/users?page=1", nil))
+		assertPassed(t, 2, &e)
+
+		if len(b.violations) != 0 {
+			t.Errorf("Expected no ordering violations, got %q", b.violations)
+		}
+	})
+
+	t.Run("An expectation may be ordered after several others", func(t *testing.T) {
+		e := Expecter{}
+		a := e.ExpectReq("POST", "/a")
+		b := e.ExpectReq("POST", "/b")
+		c := e.ExpectReq("GET", "/c").After(a).After(b)
+
+		e.LogReq(httptest.NewRequest("POST", "/a", nil))
+		e.LogReq(httptest.NewRequest("GET", "/c", nil))
+		e.LogReq(httptest.NewRequest("POST", "/b", nil))
+		assertPassedFailed(t, 2, 1, &e)
+
+		want := "ordering violation, matched before POST /b (observed order: POST /a, GET /c, POST /b)"
+		if got := c.failureReason(); got != want {
+			t.Errorf("failureReason: got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("After panics when given the same expectation", func(t *testing.T) {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Errorf("Expected After to panic")
+			}
+		}()
+		e := Expecter{}
+		a := e.ExpectReq("POST", "/a")
+		a.After(a)
+	})
+
+	t.Run("After panics when given an expectation from another Expecter", func(t *testing.T) {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Errorf("Expected After to panic")
+			}
+		}()
+		e1, e2 := Expecter{}, Expecter{}
+		e1.ExpectReq("POST", "/a").After(e2.ExpectReq("POST", "/a"))
+	})
+}
@@ -0,0 +1,141 @@
+package hex
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Path is a path template, which matches request paths segment by segment and captures named parameters:
+//
+//   server.ExpectReq("GET", hex.Path("/users/{id}/posts/{postID:[0-9]+}"))
+//
+// A parameter written as {name} matches a single non-empty path segment. A parameter written as {name:pattern}
+// matches the given regular expression, which may span several segments. Everything outside of braces is matched
+// literally, and the template must match the entire path.
+//
+// The values captured when a request matches an expectation's path template are available to mock responses via
+// PathParams.
+type Path string
+
+type pathParamsKey struct{}
+
+// PathParams returns the parameters captured by the path template of the expectation that matched the request, or
+// nil if the expectation's path was not a Path template. It's intended for use in RespondWithFn handlers:
+//
+//   server.ExpectReq("GET", hex.Path("/users/{id}")).RespondWithFn(func(rw http.ResponseWriter, req *http.Request) {
+//     fmt.Fprintf(rw, `{"id": %q}`, hex.PathParams(req)["id"])
+//   })
+func PathParams(req *http.Request) map[string]string {
+	params, _ := req.Context().Value(pathParamsKey{}).(map[string]string)
+	return params
+}
+
+// withPathParams returns a shallow copy of req carrying the parameters captured by the expectation's path template
+func (e *Expectation) withPathParams(req *http.Request) *http.Request {
+	tmpl, ok := e.path.(*pathTemplateMatcher)
+	if !ok {
+		return req
+	}
+	return req.WithContext(context.WithValue(req.Context(), pathParamsKey{}, tmpl.params(req.URL.Path)))
+}
+
+type pathTemplateMatcher struct {
+	template string
+	pattern  *regexp.Regexp
+	names    []string
+}
+
+var _ stringMatcher = &pathTemplateMatcher{}
+
+// compilePathTemplate converts a path template into an anchored regular expression, with one capture group per
+// parameter
+func compilePathTemplate(template string) (*pathTemplateMatcher, error) {
+	m := &pathTemplateMatcher{template: template}
+	expr := &strings.Builder{}
+	expr.WriteString("^")
+
+	for rest := template; rest != ""; {
+		open := strings.IndexByte(rest, '{')
+		if open == -1 {
+			if strings.IndexByte(rest, '}') != -1 {
+				return nil, fmt.Errorf("path template %q has unbalanced braces", template)
+			}
+			expr.WriteString(regexp.QuoteMeta(rest))
+			break
+		}
+		if strings.IndexByte(rest[:open], '}') != -1 {
+			return nil, fmt.Errorf("path template %q has unbalanced braces", template)
+		}
+		expr.WriteString(regexp.QuoteMeta(rest[:open]))
+
+		// Find the matching close brace, allowing for braces within the parameter's pattern, ie {id:[0-9]{3}}
+		depth, end := 0, -1
+		for i := open; i < len(rest) && end == -1; i++ {
+			switch rest[i] {
+			case '{':
+				depth++
+			case '}':
+				if depth--; depth == 0 {
+					end = i
+				}
+			}
+		}
+		if end == -1 {
+			return nil, fmt.Errorf("path template %q has unbalanced braces", template)
+		}
+
+		name, pattern := rest[open+1:end], "[^/]+"
+		if colon := strings.IndexByte(name, ':'); colon != -1 {
+			name, pattern = name[:colon], name[colon+1:]
+		}
+		if name == "" || pattern == "" {
+			return nil, fmt.Errorf("path template %q has an empty parameter name or pattern", template)
+		}
+		for _, existing := range m.names {
+			if existing == name {
+				return nil, fmt.Errorf("path template %q has duplicate parameter %q", template, name)
+			}
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("path template %q has invalid pattern for parameter %q: %s", template, name, err)
+		}
+
+		// Parameter names needn't be valid group names, so groups are named by position and looked up in params
+		fmt.Fprintf(expr, "(?P<p%d>%s)", len(m.names), pattern)
+		m.names = append(m.names, name)
+		rest = rest[end+1:]
+	}
+
+	expr.WriteString("$")
+	pattern, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("path template %q is invalid: %s", template, err)
+	}
+	m.pattern = pattern
+
+	return m, nil
+}
+
+func (m *pathTemplateMatcher) match(candidate string) bool {
+	return m.pattern.MatchString(candidate)
+}
+
+func (m *pathTemplateMatcher) String() string {
+	return m.template
+}
+
+// params returns the values of each parameter captured from path, or nil if path doesn't match the template
+func (m *pathTemplateMatcher) params(path string) map[string]string {
+	submatches := m.pattern.FindStringSubmatch(path)
+	if submatches == nil {
+		return nil
+	}
+	params := make(map[string]string, len(m.names))
+	for i, name := range m.names {
+		params[name] = submatches[m.pattern.SubexpIndex(fmt.Sprintf("p%d", i))]
+	}
+	return params
+}
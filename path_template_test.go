@@ -0,0 +1,97 @@
+package hex
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestPathTemplate(t *testing.T) {
+	testCases := []struct {
+		template string
+		path     string
+		want     map[string]string
+	}{
+		{"/users", "/users", map[string]string{}},
+		{"/users", "/users/1", nil},
+		{"/users/{id}", "/users/123", map[string]string{"id": "123"}},
+		{"/users/{id}", "/users/", nil},
+		{"/users/{id}", "/users/1/posts", nil},
+		{"/users/{id}/posts/{postID}", "/users/1/posts/abc", map[string]string{"id": "1", "postID": "abc"}},
+		{"/users/{id:[0-9]+}", "/users/123", map[string]string{"id": "123"}},
+		{"/users/{id:[0-9]+}", "/users/bob", nil},
+		{"/users/{id:[0-9]{3}}", "/users/123", map[string]string{"id": "123"}},
+		{"/users/{id:[0-9]{3}}", "/users/1234", nil},
+		{"/files/{path:.+}", "/files/a/b/c.txt", map[string]string{"path": "a/b/c.txt"}},
+		{"/v1.0/{name}.json", "/v1.0/bob.json", map[string]string{"name": "bob"}},
+		{"/v1.0/{name}.json", "/v1x0/bob.json", nil},
+		{"/items/{kind:(a|b)}-{n:(\\d)+}", "/items/b-42", map[string]string{"kind": "b", "n": "42"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.template+" "+tc.path, func(t *testing.T) {
+			m, err := makeStringMatcher(Path(tc.template))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if got, want := m.match(tc.path), tc.want != nil; got != want {
+				t.Errorf("match: got %t, want %t", got, want)
+			}
+
+			if got := m.(*pathTemplateMatcher).params(tc.path); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("params: got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPathTemplateInvalid(t *testing.T) {
+	for _, template := range []string{
+		"/users/{id",
+		"/users/id}",
+		"/users/{}",
+		"/users/{id:}",
+		"/users/{id}/{id}",
+		"/users/{id:[0-9}",
+	} {
+		if _, err := makeStringMatcher(Path(template)); err == nil {
+			t.Errorf("Expected an error for path template %q", template)
+		}
+	}
+}
+
+func TestPathParams(t *testing.T) {
+	t.Run("Parameters are available to mock responses", func(t *testing.T) {
+		s := NewServer(t, nil)
+		s.ExpectReq("GET", Path("/users/{id}")).RespondWithFn(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Write([]byte(PathParams(req)["id"]))
+		})
+
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", "/users/123", nil))
+		if got := rec.Body.String(); got != "123" {
+			t.Errorf("Expected response body 123, got %q", got)
+		}
+	})
+
+	t.Run("Parameters are available to the original handler", func(t *testing.T) {
+		var got map[string]string
+		s := NewServer(t, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			got = PathParams(req)
+		}))
+		s.ExpectReq("GET", Path("/users/{id}"))
+
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/123", nil))
+		if !reflect.DeepEqual(got, map[string]string{"id": "123"}) {
+			t.Errorf("Expected path params map[id:123], got %v", got)
+		}
+	})
+
+	t.Run("Requests without a path template have no parameters", func(t *testing.T) {
+		if got := PathParams(httptest.NewRequest("GET", "/users/123", nil)); got != nil {
+			t.Errorf("Expected nil path params, got %v", got)
+		}
+	})
+}
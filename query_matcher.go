@@ -15,16 +15,28 @@ import (
 //   WithQuery(hex.P{"key1": "value1", "key2": "value2"}) // match against multiple key/value pairs
 //   WithQuery(hex.P{"key1": hex.R(`^value\d$`)}) // mix-and-match strings, regular expressions and key/value maps
 func (exp *Expectation) WithQuery(args ...interface{}) *Expectation {
+	return exp.withQuery("WithQuery", args, false)
+}
+
+// WithExactQuery matches against the query string like WithQuery, but additionally fails if the query string
+// contains any keys, or any values for a key, that aren't mentioned by the arguments:
+//
+//   WithExactQuery(hex.P{"q": "cats"}) // passes ?q=cats, fails ?q=cats&debug=1 and ?q=cats&q=dogs
+func (exp *Expectation) WithExactQuery(args ...interface{}) *Expectation {
+	return exp.withQuery("WithExactQuery", args, true)
+}
+
+func (exp *Expectation) withQuery(name string, args []interface{}, exact bool) *Expectation {
 	matcher, err := makeURLValuesMatcher(args)
 	if err != nil {
-		panic(fmt.Sprintf("WithQuery: %s", err.Error()))
+		panic(fmt.Sprintf("%s: %s", name, err.Error()))
 	}
 
-	exp.matchers = append(exp.matchers, &queryMatcher{
+	return exp.addMatcher(&queryMatcher{
 		args:             args,
+		exact:            exact,
 		urlValuesMatcher: matcher,
 	})
-	return exp
 }
 
 type queryMatcher struct {
@@ -33,13 +45,14 @@ type queryMatcher struct {
 	urlValuesMatcher
 }
 
-func (q *queryMatcher) matches(req *http.Request) bool {
-	query := req.URL.Query()
-	return q.urlValuesMatcher.matches(query)
-
+func (q *queryMatcher) matches(req *http.Request) matchResult {
+	return q.urlValuesMatcher.result(req.URL.Query(), q.exact)
 }
 
 func (q *queryMatcher) String() string {
+	if q.exact {
+		return fmt.Sprintf("query string exactly matching %s", matcherArgsToString(q.args))
+	}
 	return fmt.Sprintf("query string matching %s", matcherArgsToString(q.args))
 }
 
@@ -42,3 +42,63 @@ func TestQueryMatcher(t *testing.T) {
 		t.Errorf("WithQuery(\"name\", \"bob\") did not match ?name=bob")
 	}
 }
+
+func ExampleExpectation_WithExactQuery() {
+	e := Expecter{}
+
+	e.ExpectReq("GET", "/search").WithExactQuery(P{"q": "cats"})
+
+	e.LogReq(httptest.NewRequest("GET", "/search?q=cats&utm_source=test", nil))
+
+	fmt.Println(e.Summary())
+	// Output:
+	// Expectations
+	// 	GET /search with query string exactly matching map[q:cats] - failed, no matching requests
+	// 		closest unmatched request: GET /search
+	// 			method GET: passed
+	// 			path /search: passed
+	// 			query string exactly matching map[q:cats]: failed, got unexpected key "utm_source"
+	// Unmatched Requests
+	// 	GET /search
+}
+
+func TestExactQueryMatcher(t *testing.T) {
+	testCases := []struct {
+		args   []interface{}
+		query  string
+		actual string
+	}{
+		{[]interface{}{P{"q": "cats"}}, "q=cats", ""},
+		{[]interface{}{P{"q": "cats"}}, "q=cats&q=cats", ""},
+		{[]interface{}{P{"q": "cats"}}, "q=dogs", `got q=["dogs"]`},
+		{[]interface{}{P{"q": "cats"}}, "q=cats&debug=1", `got unexpected key "debug"`},
+		{[]interface{}{P{"q": "cats"}}, "q=cats&q=dogs", `got unexpected value "dogs" for key "q"`},
+		{[]interface{}{P{"q": "cats"}}, "z=1&q=cats&a=2", `got unexpected key "a", unexpected key "z"`},
+		{[]interface{}{"q", R("^(cats|dogs)$")}, "q=cats&q=dogs", ""},
+		{[]interface{}{P{"q": "cats", R("^utm_"): Any}}, "q=cats&utm_source=x&utm_medium=y", ""},
+		{[]interface{}{AnyOf(P{"q": "cats"}, P{"tag": "cats"})}, "q=cats&tag=cats", ""},
+		{[]interface{}{AnyOf(P{"q": "cats"}, P{"tag": "cats"})}, "q=cats&tag=dogs", `got unexpected value "dogs" for key "tag"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			e := Expecter{}
+			exp := e.ExpectReq("GET", "/search").WithExactQuery(tc.args...)
+			e.LogReq(httptest.NewRequest("GET", "/search?"+tc.query, nil))
+
+			if tc.actual == "" {
+				if !e.Pass() {
+					t.Errorf("Expected %s to pass\n%s", exp.String(), e.Summary())
+				}
+				return
+			}
+
+			if exp.closest == nil {
+				t.Fatalf("Expected %s to fail", exp.String())
+			}
+			if got := exp.closest.checks[2].actual; got != tc.actual {
+				t.Errorf("Got %q, want %q", got, tc.actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,369 @@
+package hex
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// RecordEnv is the environment variable that puts recording servers into record mode. Any value accepted by
+// strconv.ParseBool as true, such as 1 or true, enables recording.
+const RecordEnv = "HEX_RECORD"
+
+// Redacted replaces the values of redacted headers in cassettes. When replaying, a query string value of Redacted
+// matches any value.
+const Redacted = "REDACTED"
+
+// Cassette is the file format used by NewRecordingServer to store requests and responses
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a request and the response the upstream server gave to it
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request stored in a Cassette
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  url.Values  `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+
+	// BinaryBody holds the body instead of Body if it isn't valid UTF-8
+	BinaryBody []byte `json:"binary_body,omitempty"`
+}
+
+// RecordedResponse is a response stored in a Cassette
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+
+	// BinaryBody holds the body instead of Body if it isn't valid UTF-8
+	BinaryBody []byte `json:"binary_body,omitempty"`
+}
+
+// RecordingServer is a Server which records the responses of a real upstream server to a cassette file, and replays
+// them in later runs. See NewRecordingServer.
+type RecordingServer struct {
+	*Server
+
+	upstream  *url.URL
+	cassette  string
+	recording bool
+
+	// mu guards the recorded interactions and redactors, as requests may be served concurrently
+	mu           sync.Mutex
+	interactions []Interaction
+	redactors    []func(*Interaction)
+}
+
+// NewRecordingServer returns a Server that records and replays the responses of the server at upstreamURL, so tests
+// which depend on a real API can run offline.
+//
+// When the environment variable HEX_RECORD is set to a true value, such as 1, the server is in record mode: requests
+// are forwarded to upstreamURL, and each request and response is written to the cassette file at cassettePath when the
+// test ends. Otherwise the server is in replay mode: each recorded request becomes an expectation, matched by method,
+// path and query string, which responds with the recorded responses in the order they were recorded.
+//
+//   server := hex.NewRecordingServer(t, "https://api.example.com", "testdata/users.json")
+//   server.ExpectReq("GET", "/users")
+//
+//   client := NewClient(server.URL)
+//   ...
+//
+// Recorded expectations are created with AtLeast(0), so replaying doesn't fail tests that make fewer requests than were
+// recorded. Use ExpectReq to make assertions about requests as with any other Server. In replay mode, requests with no
+// recorded response receive a 502 response and fail the test.
+//
+// The Authorization, Proxy-Authorization and Cookie request headers and the Set-Cookie response header are redacted
+// before the cassette is written. Use RedactHeaders or Redact to remove other secrets.
+func NewRecordingServer(t TestingT, upstreamURL, cassettePath string) *RecordingServer {
+	t.Helper()
+
+	upstream, err := url.Parse(upstreamURL)
+	if err != nil {
+		panic(fmt.Sprintf("NewRecordingServer: invalid upstream URL %q: %s", upstreamURL, err.Error()))
+	}
+
+	r := &RecordingServer{
+		upstream:  upstream,
+		cassette:  cassettePath,
+		recording: recordingEnabled(),
+	}
+	r.RedactHeaders("Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie")
+
+	if r.recording {
+		r.Server = NewServer(t, http.HandlerFunc(r.record))
+		t.Cleanup(func() {
+			t.Helper()
+			if err := r.save(); err != nil {
+				t.Errorf("NewRecordingServer: cannot write cassette: %s", err.Error())
+			}
+		})
+		return r
+	}
+
+	r.Server = NewServer(t, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		t.Errorf("NewRecordingServer: no recorded response for %s %s in %s, set %s=1 to record it",
+			req.Method, req.URL.RequestURI(), cassettePath, RecordEnv)
+		http.Error(rw, "hex: no recorded response", http.StatusBadGateway)
+	}))
+
+	cassette, err := loadCassette(cassettePath)
+	if err != nil {
+		t.Errorf("NewRecordingServer: cannot read cassette, set %s=1 to record it: %s", RecordEnv, err.Error())
+		return r
+	}
+	r.replay(cassette)
+	return r
+}
+
+// Recording returns true if the server is in record mode, and false if it's replaying a cassette
+func (r *RecordingServer) Recording() bool {
+	return r.recording
+}
+
+// RedactHeaders replaces the values of the given request and response headers with "REDACTED" before the cassette is
+// written
+func (r *RecordingServer) RedactHeaders(names ...string) *RecordingServer {
+	return r.Redact(func(i *Interaction) {
+		for _, name := range names {
+			for _, header := range []http.Header{i.Request.Header, i.Response.Header} {
+				if values, ok := header[http.CanonicalHeaderKey(name)]; ok {
+					for j := range values {
+						values[j] = Redacted
+					}
+				}
+			}
+		}
+	})
+}
+
+// Redact adds a function that can modify each interaction before the cassette is written, for removing secrets from
+// bodies or query strings. Redactors run in the order they're added. Query string values replaced with Redacted match
+// any value when the cassette is replayed.
+//
+//   server.Redact(func(i *hex.Interaction) {
+//     i.Request.Query.Set("api_key", hex.Redacted)
+//   })
+func (r *RecordingServer) Redact(fn func(*Interaction)) *RecordingServer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.redactors = append(r.redactors, fn)
+	return r
+}
+
+func recordingEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv(RecordEnv))
+	return err == nil && enabled
+}
+
+// hopHeaders are specific to a single connection, and aren't forwarded or recorded
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// upstreamClient forwards requests without following redirects, so that redirects are recorded like any other response
+var upstreamClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// record forwards a request to the upstream server, and records the request and response
+func (r *RecordingServer) record(rw http.ResponseWriter, req *http.Request) {
+	reqBody, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(rw, "hex: cannot read request body: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	target := *r.upstream
+	target.Path = strings.TrimSuffix(target.Path, "/") + req.URL.Path
+	target.RawPath = ""
+	target.RawQuery = req.URL.RawQuery
+
+	out, err := http.NewRequestWithContext(req.Context(), req.Method, target.String(), strings.NewReader(string(reqBody)))
+	if err != nil {
+		http.Error(rw, "hex: cannot forward request: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	out.Header = req.Header.Clone()
+	removeHopHeaders(out.Header)
+	// Let the transport negotiate compression, so that bodies are recorded decompressed
+	out.Header.Del("Accept-Encoding")
+
+	resp, err := upstreamClient.Do(out)
+	if err != nil {
+		http.Error(rw, "hex: cannot forward request: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		http.Error(rw, "hex: cannot read upstream response: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	header := resp.Header.Clone()
+	removeHopHeaders(header)
+	header.Del("Content-Length")
+
+	for key, values := range header {
+		rw.Header()[key] = values
+	}
+	rw.WriteHeader(resp.StatusCode)
+	rw.Write(respBody)
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			Path:   req.URL.Path,
+			Query:  req.URL.Query(),
+			Header: out.Header,
+		},
+		Response: RecordedResponse{
+			Status: resp.StatusCode,
+			Header: header.Clone(),
+		},
+	}
+	interaction.Request.Body, interaction.Request.BinaryBody = encodeRecordedBody(reqBody)
+	interaction.Response.Body, interaction.Response.BinaryBody = encodeRecordedBody(respBody)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, interaction)
+}
+
+func removeHopHeaders(header http.Header) {
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+}
+
+// encodeRecordedBody stores body as a string if possible, so cassettes are readable and can be edited by hand
+func encodeRecordedBody(body []byte) (string, []byte) {
+	if utf8.Valid(body) {
+		return string(body), nil
+	}
+	return "", body
+}
+
+func decodeRecordedBody(body string, binary []byte) string {
+	if binary != nil {
+		return string(binary)
+	}
+	return body
+}
+
+// save redacts the recorded interactions and writes them to the cassette file
+func (r *RecordingServer) save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.interactions {
+		for _, redact := range r.redactors {
+			redact(&r.interactions[i])
+		}
+	}
+
+	data, err := json.MarshalIndent(Cassette{Interactions: r.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.cassette), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.cassette, append(data, '\n'), 0644)
+}
+
+func loadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cassette := &Cassette{}
+	if err := json.Unmarshal(data, cassette); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	return cassette, nil
+}
+
+// replay creates an expectation for each distinct recorded request, responding with the sequence of responses
+// recorded for it
+func (r *RecordingServer) replay(cassette *Cassette) {
+	var order []string
+	responses := map[string][]RecordedResponse{}
+	requests := map[string]RecordedRequest{}
+
+	for _, interaction := range cassette.Interactions {
+		req := interaction.Request
+		key := req.Method + " " + req.Path + "?" + req.Query.Encode()
+		if _, ok := requests[key]; !ok {
+			order = append(order, key)
+			requests[key] = req
+		}
+		responses[key] = append(responses[key], interaction.Response)
+	}
+
+	for _, key := range order {
+		req := requests[key]
+		exp := r.ExpectReq(req.Method, req.Path).addMatcher(&recordedQueryMatcher{query: req.Query}).AtLeast(0)
+		for i, resp := range responses[key] {
+			if i > 0 {
+				exp.Then()
+			}
+			header := resp.Header.Clone()
+			header.Del("Content-Length")
+			exp.RespondWithHeaders(resp.Status, header, decodeRecordedBody(resp.Body, resp.BinaryBody))
+		}
+	}
+}
+
+// recordedQueryMatcher matches a query string with exactly the recorded parameters, in any order. Recorded values that
+// were redacted match any value, so that clients can send their real secrets when replaying.
+type recordedQueryMatcher struct {
+	query url.Values
+}
+
+var _ matcher = &recordedQueryMatcher{}
+
+func (q *recordedQueryMatcher) matches(req *http.Request) matchResult {
+	got := req.URL.Query()
+	if len(got) != len(q.query) {
+		return mismatch("got %q", req.URL.RawQuery)
+	}
+	for key, want := range q.query {
+		values, ok := got[key]
+		if !ok || len(values) != len(want) {
+			return mismatch("got %q", req.URL.RawQuery)
+		}
+		for i, value := range values {
+			if want[i] != Redacted && want[i] != value {
+				return mismatch("got %q", req.URL.RawQuery)
+			}
+		}
+	}
+	return matchSuccess
+}
+
+func (q *recordedQueryMatcher) String() string {
+	if len(q.query) == 0 {
+		return "no query string"
+	}
+	return fmt.Sprintf("query string %s", q.query.Encode())
+}
@@ -0,0 +1,263 @@
+package hex
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func setRecording(t *testing.T, enabled bool) {
+	t.Helper()
+	old, wasSet := os.LookupEnv(RecordEnv)
+	if enabled {
+		os.Setenv(RecordEnv, "1")
+	} else {
+		os.Unsetenv(RecordEnv)
+	}
+	t.Cleanup(func() {
+		if wasSet {
+			os.Setenv(RecordEnv, old)
+		} else {
+			os.Unsetenv(RecordEnv)
+		}
+	})
+}
+
+func get(t *testing.T, url string, header ...string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestRecordingServer(t *testing.T) {
+	cassette := filepath.Join(t.TempDir(), "cassettes", "users.json")
+
+	var mu sync.Mutex
+	calls := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+
+		if req.Header.Get("Authorization") != "Bearer secret" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("Set-Cookie", "session=secret")
+		switch req.URL.RequestURI() {
+		case "/api/users":
+			fmt.Fprintf(rw, `[{"id": %d}]`, calls)
+		case "/api/users?page=2":
+			fmt.Fprint(rw, `[]`)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	t.Run("Records responses from the upstream server", func(t *testing.T) {
+		setRecording(t, true)
+		s := NewRecordingServer(t, upstream.URL+"/api", cassette)
+		if !s.Recording() {
+			t.Fatalf("Expected the server to be recording")
+		}
+		s.ExpectReq("GET", "/users").Times(3)
+
+		for _, want := range []string{`[{"id": 1}]`, `[{"id": 2}]`} {
+			if status, body := get(t, s.URL+"/users", "Authorization", "Bearer secret"); status != 200 || body != want {
+				t.Errorf("Expected 200 %s, got %d %s", want, status, body)
+			}
+		}
+		if status, body := get(t, s.URL+"/users?page=2", "Authorization", "Bearer secret"); status != 200 || body != "[]" {
+			t.Errorf("Expected 200 [], got %d %s", status, body)
+		}
+	})
+
+	t.Run("Redacts secrets in the cassette", func(t *testing.T) {
+		data, err := os.ReadFile(cassette)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), "secret") {
+			t.Errorf("Expected secrets to be redacted, got %s", data)
+		}
+		if !strings.Contains(string(data), Redacted) {
+			t.Errorf("Expected redacted headers to be marked, got %s", data)
+		}
+	})
+
+	upstream.Close()
+
+	t.Run("Replays recorded responses in order", func(t *testing.T) {
+		setRecording(t, false)
+		s := NewRecordingServer(t, upstream.URL+"/api", cassette)
+		if s.Recording() {
+			t.Fatalf("Expected the server to be replaying")
+		}
+		s.ExpectReq("GET", "/users").Times(4)
+
+		for _, want := range []string{`[{"id": 1}]`, `[{"id": 2}]`, `[{"id": 2}]`} {
+			if status, body := get(t, s.URL+"/users"); status != 200 || body != want {
+				t.Errorf("Expected 200 %s, got %d %s", want, status, body)
+			}
+		}
+		if status, body := get(t, s.URL+"/users?page=2"); status != 200 || body != "[]" {
+			t.Errorf("Expected 200 [], got %d %s", status, body)
+		}
+	})
+
+	t.Run("Unused recordings don't fail the test", func(t *testing.T) {
+		setRecording(t, false)
+		NewRecordingServer(t, upstream.URL+"/api", cassette)
+	})
+
+	t.Run("Requests with no recording fail the test", func(t *testing.T) {
+		setRecording(t, false)
+		mockT := &TesterMock{}
+		s := NewRecordingServer(mockT, upstream.URL+"/api", cassette)
+		defer s.Close()
+
+		if status, _ := get(t, s.URL+"/users?page=3"); status != http.StatusBadGateway {
+			t.Errorf("Expected 502, got %d", status)
+		}
+		if got := mockT.b.String(); !strings.Contains(got, "no recorded response for GET /users?page=3") {
+			t.Errorf("Expected missing recording to be reported, got %q", got)
+		}
+	})
+
+	t.Run("Missing cassettes fail the test", func(t *testing.T) {
+		setRecording(t, false)
+		mockT := &TesterMock{}
+		s := NewRecordingServer(mockT, upstream.URL, filepath.Join(t.TempDir(), "missing.json"))
+		defer s.Close()
+
+		if got := mockT.b.String(); !strings.Contains(got, "cannot read cassette, set HEX_RECORD=1 to record it") {
+			t.Errorf("Expected missing cassette to be reported, got %q", got)
+		}
+	})
+}
+
+func TestRedact(t *testing.T) {
+	r := &RecordingServer{}
+	r.RedactHeaders("X-Api-Key").Redact(func(i *Interaction) {
+		i.Request.Query.Set("token", Redacted)
+	})
+
+	r.interactions = []Interaction{{
+		Request: RecordedRequest{
+			Query:  map[string][]string{"token": {"abc"}, "page": {"2"}},
+			Header: http.Header{"X-Api-Key": {"abc"}, "Accept": {"*/*"}},
+		},
+	}}
+	r.cassette = filepath.Join(t.TempDir(), "cassette.json")
+	if err := r.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := r.interactions[0].Request
+	if got.Query.Get("token") != Redacted || got.Query.Get("page") != "2" {
+		t.Errorf("Expected token to be redacted, got %v", got.Query)
+	}
+	if got.Header.Get("X-Api-Key") != Redacted || got.Header.Get("Accept") != "*/*" {
+		t.Errorf("Expected X-Api-Key to be redacted, got %v", got.Header)
+	}
+}
+
+func TestRecordingServerRedactedQuery(t *testing.T) {
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+	upstream := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(rw, "page %s", req.URL.Query().Get("page"))
+	}))
+	defer upstream.Close()
+
+	t.Run("Record", func(t *testing.T) {
+		setRecording(t, true)
+		s := NewRecordingServer(t, upstream.URL, cassette)
+		s.Redact(func(i *Interaction) {
+			i.Request.Query.Set("api_key", Redacted)
+		})
+		get(t, s.URL+"/users?api_key=secret&page=1")
+	})
+
+	data, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("Expected api_key to be redacted, got %s", data)
+	}
+
+	t.Run("Replay", func(t *testing.T) {
+		setRecording(t, false)
+		mockT := &TesterMock{}
+		s := NewRecordingServer(mockT, upstream.URL, cassette)
+		defer s.Close()
+
+		if status, body := get(t, s.URL+"/users?page=1&api_key=another-secret"); status != 200 || body != "page 1" {
+			t.Errorf("Expected redacted query values to match any value, got %d %s", status, body)
+		}
+		if status, _ := get(t, s.URL+"/users?page=2&api_key=another-secret"); status != http.StatusBadGateway {
+			t.Errorf("Expected values that weren't redacted to match exactly, got %d", status)
+		}
+		if status, _ := get(t, s.URL+"/users?page=1"); status != http.StatusBadGateway {
+			t.Errorf("Expected redacted keys to be required, got %d", status)
+		}
+	})
+}
+
+func TestRecordingServerLargeBody(t *testing.T) {
+	setRecording(t, true)
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+	body := strings.Repeat("x", 100)
+
+	var got string
+	upstream := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		data, _ := io.ReadAll(req.Body)
+		got = string(data)
+	}))
+	defer upstream.Close()
+
+	t.Run("Record", func(t *testing.T) {
+		s := NewRecordingServer(t, upstream.URL, cassette)
+		s.SetMaxBodySize(10)
+		resp, err := http.Post(s.URL+"/upload", "text/plain", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	})
+
+	if got != body {
+		t.Errorf("Expected the upstream server to receive the full body, got %d bytes", len(got))
+	}
+
+	c, err := loadCassette(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Interactions) != 1 || c.Interactions[0].Request.Body != body {
+		t.Errorf("Expected the full body to be recorded, got %+v", c.Interactions)
+	}
+}
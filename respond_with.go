@@ -1,17 +1,37 @@
 package hex
 
 import (
+	"fmt"
 	"io"
 	"net/http"
 )
 
+// ExhaustedBehavior determines how an expectation responds once every response in its sequence has been used.
+// See Then and WhenExhausted.
+type ExhaustedBehavior int
+
+const (
+	// RepeatLast responds to every request after the sequence is exhausted with the last response. This is the default.
+	RepeatLast ExhaustedBehavior = 1
+
+	// Cycle starts again from the first response in the sequence
+	Cycle ExhaustedBehavior = 2
+
+	// FailWhenExhausted fails the expectation if it matches more requests than there are responses. Requests beyond
+	// the end of the sequence are passed to the original handler, as though no mock response was defined.
+	FailWhenExhausted ExhaustedBehavior = 3
+)
+
 // RespondWithHandler registers an alternate handler to use when the expectation matches a request.
-// Use AndCallThrough to additionally run the original handler, after the new handler is called
+// Use AndCallThrough to additionally run the original handler, after the new handler is called.
+// Use Then to register additional handlers for subsequent requests.
 func (e *Expectation) RespondWithHandler(handler http.Handler) *Expectation {
-	if e.handler != nil {
-		panic("Multiple responses defined for one hex.Expectation")
+	defer e.lock()()
+	if len(e.handlers) > 0 && !e.then {
+		panic("Multiple responses defined for one hex.Expectation. Use Then to define a sequence of responses")
 	}
-	e.handler = handler
+	e.handlers = append(e.handlers, handler)
+	e.then = false
 	return e
 }
 
@@ -30,12 +50,71 @@ func (e *Expectation) RespondWith(status int, body string) *Expectation {
 	})
 }
 
+// Then allows another mock response to be defined, which is used for the next request matching the expectation.
+// Responses are used in the order they're defined, with the first request receiving the first response:
+//
+//   server.ExpectReq("GET", "/status").
+//     RespondWith(503, "unavailable").Then().
+//     RespondWith(200, "ok")
+//
+// By default, the last response is repeated once the sequence is exhausted. See WhenExhausted.
+func (e *Expectation) Then() *Expectation {
+	defer e.lock()()
+	if len(e.handlers) == 0 {
+		panic("Then called on expectation that has no mock response. Use RespondWith to setup a mock response before calling Then")
+	}
+	e.then = true
+	return e
+}
+
+// WhenExhausted controls how the expectation responds to requests after every response in its sequence has been
+// used. Its argument is one of RepeatLast, Cycle or FailWhenExhausted.
+func (e *Expectation) WhenExhausted(behavior ExhaustedBehavior) *Expectation {
+	defer e.lock()()
+	e.exhausted = behavior
+	return e
+}
+
 // AndCallThrough instructs the expectation to run both a registered mock response handler, and then
 // additionally run the original handler
 func (e *Expectation) AndCallThrough() *Expectation {
-	if e.handler == nil {
+	defer e.lock()()
+	if len(e.handlers) == 0 {
 		panic("AndCallThrough called on expectation that has no mock response. Use WithResponse to setup a mock response before calling AndCallThrough")
 	}
 	e.callThrough = true
 	return e
 }
+
+// response is the mock response chosen for a matched request
+type response struct {
+	// handler is the mock response handler, or nil if the request should only be passed to the original handler
+	handler http.Handler
+
+	// callThrough is true if the original handler should also be called
+	callThrough bool
+}
+
+// nextResponse returns the next mock response in the sequence. It's chosen while the request is being matched, so
+// that concurrent requests receive responses in the order they were matched. The Expecter's lock must be held.
+func (e *Expectation) nextResponse() response {
+	if len(e.handlers) == 0 {
+		return response{callThrough: e.callThrough}
+	}
+
+	n := e.served
+	e.served++
+
+	if n < len(e.handlers) {
+		return response{e.handlers[n], e.callThrough}
+	}
+
+	switch e.exhausted {
+	case Cycle:
+		return response{e.handlers[n%len(e.handlers)], e.callThrough}
+	case FailWhenExhausted:
+		e.failures = append(e.failures, fmt.Sprintf("request %d exhausted the sequence of %d responses", n+1, len(e.handlers)))
+		return response{callThrough: e.callThrough}
+	}
+	return response{e.handlers[len(e.handlers)-1], e.callThrough}
+}
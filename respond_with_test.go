@@ -94,3 +94,103 @@ func TestExpectationRespondWith(t *testing.T) {
 		}
 	})
 }
+
+func ExampleExpectation_Then() {
+	server := hex.NewServer(&testing.T{}, nil)
+
+	server.ExpectReq("GET", "/status").
+		RespondWith(503, "unavailable").Then().
+		RespondWith(200, "ok")
+
+	for i := 0; i < 3; i++ {
+		resp, err := http.Get(server.URL + "/status")
+		if err != nil {
+			panic(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		fmt.Println(resp.StatusCode, string(body))
+	}
+
+	// Output:
+	// 503 unavailable
+	// 200 ok
+	// 200 ok
+}
+
+func TestExpectationResponseSequences(t *testing.T) {
+	get := func(t *testing.T, url string) (int, string) {
+		resp, err := http.Get(url)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	assertResponses := func(t *testing.T, server *hex.Server, want ...string) {
+		t.Helper()
+		for i, w := range want {
+			status, body := get(t, server.URL+"/status")
+			if got := fmt.Sprintf("%d %s", status, body); got != w {
+				t.Errorf("Response %d: got %q, want %q", i+1, got, w)
+			}
+		}
+	}
+
+	t.Run("The last response is repeated by default", func(t *testing.T) {
+		server := hex.NewServer(t, nil)
+		server.ExpectReq("GET", "/status").RespondWith(503, "a").Then().RespondWith(200, "b")
+		assertResponses(t, server, "503 a", "200 b", "200 b")
+	})
+
+	t.Run("Cycle restarts the sequence", func(t *testing.T) {
+		server := hex.NewServer(t, nil)
+		server.ExpectReq("GET", "/status").
+			RespondWith(503, "a").Then().
+			RespondWith(200, "b").
+			WhenExhausted(hex.Cycle)
+		assertResponses(t, server, "503 a", "200 b", "503 a", "200 b")
+	})
+
+	t.Run("FailWhenExhausted fails the expectation and falls through to the original handler", func(t *testing.T) {
+		server := hex.NewServer(&testing.T{}, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(404)
+			io.WriteString(rw, "fallback")
+		}))
+		server.ExpectReq("GET", "/status").
+			RespondWith(503, "a").Then().
+			RespondWith(200, "b").
+			WhenExhausted(hex.FailWhenExhausted)
+		assertResponses(t, server, "503 a", "200 b", "404 fallback")
+
+		if server.Pass() {
+			t.Errorf("Expected the expectation to fail after its responses were exhausted")
+		}
+		want := "Expectations\n\tGET /status - failed, request 3 exhausted the sequence of 2 responses\n"
+		if got := server.Summary(); got != want {
+			t.Errorf("Summary: got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Multiple responses without Then produce a panic", func(t *testing.T) {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Errorf("Expected a second RespondWith without Then to panic")
+			}
+		}()
+		e := hex.Expecter{}
+		e.ExpectReq("GET", "/status").RespondWith(503, "a").RespondWith(200, "b")
+	})
+
+	t.Run("Then without a response produces a panic", func(t *testing.T) {
+		defer func() {
+			if err := recover(); err == nil {
+				t.Errorf("Expected Then without a response to panic")
+			}
+		}()
+		e := hex.Expecter{}
+		e.ExpectReq("GET", "/status").Then()
+	})
+}
@@ -0,0 +1,105 @@
+package hex
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Response is a mock response with a status, headers, cookies and body. It implements http.Handler, so it can be given
+// to RespondWithHandler:
+//
+//   server.ExpectReq("POST", "/users").RespondWithHandler(
+//     hex.NewResponse(http.StatusCreated).
+//       Header("Location", "/users/123").
+//       Cookie(&http.Cookie{Name: "session", Value: "abc"}).
+//       JSON(hex.P{"id": 123}),
+//   )
+type Response struct {
+	status  int
+	header  http.Header
+	cookies []*http.Cookie
+	body    []byte
+}
+
+var _ http.Handler = &Response{}
+
+// NewResponse returns a Response with the given status, and no headers or body
+func NewResponse(status int) *Response {
+	return &Response{
+		status: status,
+		header: http.Header{},
+	}
+}
+
+// Header adds a header to the response
+func (r *Response) Header(key, value string) *Response {
+	r.header.Add(key, value)
+	return r
+}
+
+// Cookie adds a Set-Cookie header to the response
+func (r *Response) Cookie(cookie *http.Cookie) *Response {
+	r.cookies = append(r.cookies, cookie)
+	return r
+}
+
+// Body sets the response body
+func (r *Response) Body(body string) *Response {
+	r.body = []byte(body)
+	return r
+}
+
+// JSON sets the response body to v encoded as JSON, and sets the Content-Type header to application/json unless it
+// has already been set. It panics if v cannot be encoded.
+func (r *Response) JSON(v interface{}) *Response {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to encode JSON response: %s", err.Error()))
+	}
+	r.body = body
+	if r.header.Get("Content-Type") == "" {
+		r.header.Set("Content-Type", "application/json")
+	}
+	return r
+}
+
+// ServeHTTP writes the response
+func (r *Response) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	for key, values := range r.header {
+		for _, value := range values {
+			rw.Header().Add(key, value)
+		}
+	}
+	for _, cookie := range r.cookies {
+		http.SetCookie(rw, cookie)
+	}
+	rw.WriteHeader(r.status)
+	if len(r.body) == 0 {
+		return
+	}
+	if _, err := rw.Write(r.body); err != nil {
+		panic("Failed to write response in Response")
+	}
+}
+
+// RespondWithJSON responds with the given status and v encoded as JSON, with a Content-Type of application/json.
+// v is encoded immediately, and RespondWithJSON panics if it cannot be encoded.
+func (e *Expectation) RespondWithJSON(status int, v interface{}) *Expectation {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("RespondWithJSON: %s", err.Error()))
+	}
+	return e.RespondWithHandler(NewResponse(status).Header("Content-Type", "application/json").Body(string(body)))
+}
+
+// RespondWithHeaders responds with the given status, headers and body
+func (e *Expectation) RespondWithHeaders(status int, header http.Header, body string) *Expectation {
+	r := NewResponse(status).Body(body)
+	for key, values := range header {
+		for _, value := range values {
+			r.Header(key, value)
+		}
+	}
+	return e.RespondWithHandler(r)
+}
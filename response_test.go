@@ -0,0 +1,116 @@
+package hex_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/meagar/hex"
+)
+
+func ExampleExpectation_RespondWithJSON() {
+	server := hex.NewServer(&testing.T{}, nil)
+
+	server.ExpectReq("GET", "/users/123").RespondWithJSON(200, hex.P{"id": 123, "name": "bob"})
+
+	resp, err := http.Get(server.URL + "/users/123")
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	fmt.Println(resp.StatusCode, resp.Header.Get("Content-Type"))
+	fmt.Println(string(body))
+	// Output:
+	// 200 application/json
+	// {"id":123,"name":"bob"}
+}
+
+func ExampleResponse() {
+	server := hex.NewServer(&testing.T{}, nil)
+
+	server.ExpectReq("POST", "/users").RespondWithHandler(
+		hex.NewResponse(http.StatusCreated).
+			Header("Location", "/users/123").
+			Cookie(&http.Cookie{Name: "session", Value: "abc"}).
+			JSON(hex.P{"id": 123}),
+	)
+
+	resp, err := http.Post(server.URL+"/users", "application/json", nil)
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	fmt.Println(resp.StatusCode, resp.Header.Get("Location"), resp.Cookies()[0].Value)
+	fmt.Println(resp.Header.Get("Content-Type"), string(body))
+	// Output:
+	// 201 /users/123 abc
+	// application/json {"id":123}
+}
+
+func TestRespondWithHeaders(t *testing.T) {
+	server := hex.NewServer(t, nil)
+	server.ExpectReq("GET", "/report").RespondWithHeaders(200, http.Header{
+		"Content-Type":  {"text/csv"},
+		"Cache-Control": {"no-cache", "no-store"},
+	}, "a,b\n1,2\n")
+
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest("GET", "/report", nil))
+
+	if got := rec.Header().Get("Content-Type"); got != "text/csv" {
+		t.Errorf("Content-Type: got %q, want text/csv", got)
+	}
+	if got := rec.Header().Values("Cache-Control"); len(got) != 2 {
+		t.Errorf("Cache-Control: got %q, want two values", got)
+	}
+	if got := rec.Body.String(); got != "a,b\n1,2\n" {
+		t.Errorf("Body: got %q", got)
+	}
+}
+
+func TestResponse(t *testing.T) {
+	t.Run("JSON does not override an explicit Content-Type", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		hex.NewResponse(200).Header("Content-Type", "application/vnd.api+json").JSON([]int{1, 2}).
+			ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+		if got := rec.Header().Get("Content-Type"); got != "application/vnd.api+json" {
+			t.Errorf("Content-Type: got %q", got)
+		}
+		if got := rec.Body.String(); got != "[1,2]" {
+			t.Errorf("Body: got %q", got)
+		}
+	})
+
+	t.Run("Responses can be used in sequences", func(t *testing.T) {
+		server := hex.NewServer(t, nil)
+		server.ExpectReq("GET", "/status").
+			RespondWithJSON(503, hex.P{"status": "starting"}).Then().
+			RespondWithHandler(hex.NewResponse(204))
+
+		for _, want := range []int{503, 204, 204} {
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest("GET", "/status", nil))
+			if rec.Code != want {
+				t.Errorf("Got status %d, want %d", rec.Code, want)
+			}
+		}
+	})
+
+	t.Run("RespondWithJSON panics on values that cannot be encoded", func(t *testing.T) {
+		defer func() {
+			want := "RespondWithJSON: json: unsupported type: chan int"
+			if got := fmt.Sprint(recover()); got != want {
+				t.Errorf("Got panic %q, want %q", got, want)
+			}
+		}()
+		e := hex.Expecter{}
+		e.ExpectReq("GET", "/").RespondWithJSON(200, make(chan int))
+	})
+}
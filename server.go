@@ -27,6 +27,7 @@ func NewServer(t TestingT, handler http.Handler) *Server {
 	s.URL = s.Server.URL
 	t.Cleanup(func() {
 		t.Helper()
+		s.closeHungConnections()
 		s.HexReport(t)
 	})
 
@@ -46,25 +47,49 @@ func NewTLSServer(t TestingT, handler http.Handler) *Server {
 	s.URL = s.Server.URL
 	t.Cleanup(func() {
 		t.Helper()
+		s.closeHungConnections()
 		s.HexReport(t)
 	})
 
-	x := new(string)
-	*x = "foobar"
-
 	return &s
 }
 
+// Close shuts down the underlying httptest.Server, and then closes any connections held open by RespondWithHang or
+// ExpectWebSocket
+func (s *Server) Close() {
+	// httptest.Server waits for handlers until they hijack their connections, by which point they have been tracked
+	s.Server.Close()
+	s.closeHungConnections()
+}
+
 // ServeHTTP logs requests that come through the server so they can be matched against expectations, and
 // evalutes any mock responses defined for matched expectations.
 func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
-	exp := s.LogReq(req)
+	exp, resp, err := s.logReq(req)
+	if err != nil {
+		http.Error(rw, "hex: cannot read request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	if exp != nil && exp.handler != nil {
-		exp.handler.ServeHTTP(rw, req)
-		if exp.callThrough == false {
+	if exp == nil && s.respondToUnmatched(rw, req) {
+		return
+	}
+
+	if exp != nil {
+		req = exp.withPathParams(req)
+		var waiting bool
+		if rw, waiting = exp.delay(rw, req); !waiting {
 			return
 		}
+		if resp.handler != nil {
+			// The mock response may consume the body, which the original handler should also be able to read in full
+			replay := replayBody(req)
+			resp.handler.ServeHTTP(rw, req)
+			if resp.callThrough == false {
+				return
+			}
+			replay()
+		}
 	}
 
 	if s.handler != nil {
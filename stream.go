@@ -0,0 +1,128 @@
+package hex
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Chunk is one write of a streamed response, see RespondWithStream
+type Chunk struct {
+	// Data is written to the response and flushed to the client
+	Data string
+
+	// Delay is how long to wait before writing Data
+	Delay time.Duration
+}
+
+// RespondWithStream responds with the given status, and then writes each chunk in turn, waiting for the chunk's delay
+// before writing it and flushing it to the client immediately afterwards:
+//
+//   server.ExpectReq("GET", "/export").RespondWithStream(200,
+//     hex.Chunk{Data: "id,name\n"},
+//     hex.Chunk{Data: "1,alice\n", Delay: 100 * time.Millisecond},
+//     hex.Chunk{Data: "2,bob\n", Delay: 100 * time.Millisecond},
+//   )
+//
+// Like WithDelay, delays are abandoned if the client gives up, and the summary records the cancellation. See
+// RespondWithEvents for streams of server-sent events.
+func (e *Expectation) RespondWithStream(status int, chunks ...Chunk) *Expectation {
+	return e.RespondWithFn(func(rw http.ResponseWriter, req *http.Request) {
+		e.stream(rw, req, status, chunks)
+	})
+}
+
+// stream writes the status and then each chunk, flushing after each write
+func (e *Expectation) stream(rw http.ResponseWriter, req *http.Request, status int, chunks []Chunk) {
+	rw.WriteHeader(status)
+	flush(rw)
+	for _, chunk := range chunks {
+		if !e.wait(req, chunk.Delay) {
+			return
+		}
+		if _, err := io.WriteString(rw, chunk.Data); err != nil {
+			// The client has gone away
+			return
+		}
+		flush(rw)
+	}
+}
+
+// Event is a server-sent event, see RespondWithEvents
+type Event struct {
+	// ID sets the client's last event ID, which it sends in the Last-Event-ID header when it reconnects
+	ID string
+
+	// Event is the event's type. Clients treat events without a type as "message" events.
+	Event string
+
+	// Data is the event's payload. Multi-line data is sent as multiple data fields.
+	Data string
+
+	// Retry, if non-zero, tells the client how long to wait before reconnecting
+	Retry time.Duration
+
+	// Delay is how long to wait before sending the event
+	Delay time.Duration
+}
+
+// String returns the event in the text/event-stream format, including the blank line that ends it
+func (ev Event) String() string {
+	buf := &strings.Builder{}
+	if ev.ID != "" {
+		fmt.Fprintf(buf, "id: %s\n", ev.ID)
+	}
+	if ev.Event != "" {
+		fmt.Fprintf(buf, "event: %s\n", ev.Event)
+	}
+	if ev.Retry > 0 {
+		fmt.Fprintf(buf, "retry: %d\n", ev.Retry.Milliseconds())
+	}
+	for _, line := range strings.Split(ev.Data, "\n") {
+		fmt.Fprintf(buf, "data: %s\n", line)
+	}
+	buf.WriteString("\n")
+	return buf.String()
+}
+
+// RespondWithEvents responds with a text/event-stream of server-sent events, flushing each event to the client as it's
+// sent:
+//
+//   server.ExpectReq("GET", "/events").RespondWithEvents(
+//     hex.Event{ID: "1", Event: "created", Data: `{"id": 1}`},
+//     hex.Event{ID: "2", Event: "updated", Data: `{"id": 1}`, Delay: time.Second},
+//   ).AtLeast(1)
+//
+// When a client reconnects with a Last-Event-ID header naming one of the events, only the events after it are sent. If
+// the ID doesn't match any event, every event is sent again.
+func (e *Expectation) RespondWithEvents(events ...Event) *Expectation {
+	return e.RespondWithFn(func(rw http.ResponseWriter, req *http.Request) {
+		remaining := events
+		if lastID := req.Header.Get("Last-Event-ID"); lastID != "" {
+			for i, ev := range events {
+				if ev.ID == lastID {
+					remaining = events[i+1:]
+					break
+				}
+			}
+		}
+
+		chunks := make([]Chunk, len(remaining))
+		for i, ev := range remaining {
+			chunks[i] = Chunk{Data: ev.String(), Delay: ev.Delay}
+		}
+
+		rw.Header().Set("Content-Type", "text/event-stream")
+		rw.Header().Set("Cache-Control", "no-cache")
+		e.stream(rw, req, http.StatusOK, chunks)
+	})
+}
+
+// flush sends any buffered data to the client, if rw supports it
+func flush(rw http.ResponseWriter) {
+	if f, ok := rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
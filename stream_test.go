@@ -0,0 +1,120 @@
+package hex
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRespondWithStream(t *testing.T) {
+	t.Run("Writes and flushes each chunk", func(t *testing.T) {
+		s := NewServer(t, nil)
+		s.ExpectReq("GET", "/stream").RespondWithStream(200,
+			Chunk{Data: "first\n"},
+			Chunk{Data: "second\n", Delay: 200 * time.Millisecond},
+		)
+
+		start := time.Now()
+		resp, err := http.Get(s.URL + "/stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		// The first chunk should arrive without waiting for the second
+		r := bufio.NewReader(resp.Body)
+		if line, err := r.ReadString('\n'); line != "first\n" || err != nil {
+			t.Fatalf("Expected first chunk, got %q, %v", line, err)
+		}
+		if elapsed := time.Since(start); elapsed >= 200*time.Millisecond {
+			t.Errorf("Expected first chunk to be flushed immediately, took %s", elapsed)
+		}
+
+		rest, err := io.ReadAll(r)
+		if string(rest) != "second\n" || err != nil {
+			t.Errorf("Expected second chunk, got %q, %v", rest, err)
+		}
+		if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+			t.Errorf("Expected second chunk to be delayed by 200ms, took %s", elapsed)
+		}
+	})
+
+	t.Run("Stops streaming when the client gives up", func(t *testing.T) {
+		s := &Server{}
+		s.ExpectReq("GET", "/stream").RespondWithStream(200, Chunk{Data: "a"}, Chunk{Data: "b", Delay: time.Minute})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", "/stream", nil).WithContext(ctx))
+		if rec.Body.String() != "a" {
+			t.Errorf("Body: got %q, want %q", rec.Body.String(), "a")
+		}
+		if summary := s.Summary(); !strings.Contains(summary, "client cancelled GET /stream") {
+			t.Errorf("Expected summary to record the cancellation, got %q", summary)
+		}
+	})
+}
+
+func TestEventString(t *testing.T) {
+	testCases := []struct {
+		event Event
+		want  string
+	}{
+		{Event{Data: "hello"}, "data: hello\n\n"},
+		{Event{ID: "1", Event: "update", Data: "a\nb"}, "id: 1\nevent: update\ndata: a\ndata: b\n\n"},
+		{Event{Retry: 3 * time.Second, Data: ""}, "retry: 3000\ndata: \n\n"},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.event.String(); got != tc.want {
+			t.Errorf("%#v: got %q, want %q", tc.event, got, tc.want)
+		}
+	}
+}
+
+func TestRespondWithEvents(t *testing.T) {
+	events := []Event{
+		{ID: "1", Data: "one"},
+		{ID: "2", Data: "two"},
+		{ID: "3", Data: "three"},
+	}
+
+	testCases := []struct {
+		name        string
+		lastEventID string
+		want        string
+	}{
+		{"without Last-Event-ID", "", "id: 1\ndata: one\n\nid: 2\ndata: two\n\nid: 3\ndata: three\n\n"},
+		{"resuming after Last-Event-ID", "2", "id: 3\ndata: three\n\n"},
+		{"with an unknown Last-Event-ID", "99", "id: 1\ndata: one\n\nid: 2\ndata: two\n\nid: 3\ndata: three\n\n"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewServer(t, nil)
+			s.ExpectReq("GET", "/events").RespondWithEvents(events...)
+
+			req := httptest.NewRequest("GET", "/events", nil)
+			if tc.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tc.lastEventID)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+				t.Errorf("Content-Type: got %q, want text/event-stream", got)
+			}
+			if got := rec.Body.String(); got != tc.want {
+				t.Errorf("Body: got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
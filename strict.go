@@ -0,0 +1,75 @@
+package hex
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Strict puts the Expecter into strict mode, in which any request that doesn't match an expectation causes the
+// Expecter to fail, and is reported as a failure by HexReport.
+// To limit strict mode to a single scope, use DoStrict.
+func (e *Expecter) Strict() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.strict = true
+}
+
+// RespondToUnmatched causes a Server to respond to any request that doesn't match an expectation with the given
+// status, rather than passing it to the Server's handler. This lets a client see the error immediately, for example
+// with http.StatusNotImplemented. A status of zero restores the default behavior.
+func (e *Expecter) RespondToUnmatched(status int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.unmatchedStatus = status
+}
+
+// DoStrict is like Do, but any request logged within the scope that doesn't match an expectation causes the Expecter
+// to fail, as though Strict had been called for the duration of fn.
+func (e *Expectation) DoStrict(fn func()) {
+	e.setStrictScope(true)
+	defer e.setStrictScope(false)
+	e.Do(fn)
+}
+
+func (e *Expectation) setStrictScope(strict bool) {
+	defer e.lock()()
+	e.strictScope = strict
+}
+
+// inStrictScope returns true if requests logged now are subject to strict mode. The Expecter's lock must be held.
+func (e *Expecter) inStrictScope() bool {
+	if e.strict {
+		return true
+	}
+	for exp := e.current; exp != e.root; exp = exp.parent {
+		if exp.strictScope {
+			return true
+		}
+	}
+	return false
+}
+
+// isStrictUnmatched returns true if the request was unmatched in strict mode. The Expecter's lock must be held.
+func (e *Expecter) isStrictUnmatched(req *http.Request) bool {
+	for _, r := range e.strictUnmatched {
+		if r == req {
+			return true
+		}
+	}
+	return false
+}
+
+// respondToUnmatched writes the response configured by RespondToUnmatched, and returns false if there isn't one
+func (e *Expecter) respondToUnmatched(rw http.ResponseWriter, req *http.Request) bool {
+	e.mu.Lock()
+	status := e.unmatchedStatus
+	e.mu.Unlock()
+
+	if status == 0 {
+		return false
+	}
+
+	rw.WriteHeader(status)
+	fmt.Fprintf(rw, "hex: no expectation matched %s %s\n", req.Method, req.URL.Path)
+	return true
+}
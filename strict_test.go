@@ -0,0 +1,120 @@
+package hex
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func ExampleExpecter_Strict() {
+	e := Expecter{}
+	e.Strict()
+
+	e.ExpectReq("GET", "/status")
+
+	e.LogReq(httptest.NewRequest("GET", "/status", nil))
+	e.LogReq(httptest.NewRequest("GET", "/debug", nil))
+
+	fmt.Println(e.Summary())
+	// Output:
+	// Expectations
+	// 	GET /status - passed
+	// Unmatched Requests
+	// 	GET /debug - failed, unexpected request in strict mode
+}
+
+func TestStrict(t *testing.T) {
+	t.Run("Unmatched requests don't cause failures by default", func(t *testing.T) {
+		e := Expecter{}
+		e.LogReq(httptest.NewRequest("GET", "/debug", nil))
+		if !e.Pass() {
+			t.Errorf("Expected an unmatched request to pass outside of strict mode")
+		}
+	})
+
+	t.Run("Unmatched requests cause failures in strict mode", func(t *testing.T) {
+		e := Expecter{}
+		e.Strict()
+		e.ExpectReq("GET", "/status")
+		e.LogReq(httptest.NewRequest("GET", "/status", nil))
+		e.LogReq(httptest.NewRequest("GET", "/debug", nil))
+
+		if e.Pass() {
+			t.Errorf("Expected an unmatched request to fail in strict mode")
+		}
+
+		mockT := TesterMock{}
+		e.HexReport(&mockT)
+		want := "One or more HTTP expectations failed\nExpectations\n\tGET /status - passed\nUnmatched Requests\n\tGET /debug - failed, unexpected request in strict mode\n"
+		if got := mockT.b.String(); got != want {
+			t.Errorf("HexReport wrote\n%s\nexpected\n%s", got, want)
+		}
+	})
+
+	t.Run("DoStrict only applies strict mode within its scope", func(t *testing.T) {
+		e := Expecter{}
+		e.LogReq(httptest.NewRequest("GET", "/before", nil))
+		e.ExpectReq("GET", "/status").DoStrict(func() {
+			e.ExpectReq("GET", "/nested").Do(func() {
+				e.LogReq(httptest.NewRequest("GET", "/nested", nil))
+				e.LogReq(httptest.NewRequest("GET", "/during", nil))
+			})
+			e.LogReq(httptest.NewRequest("GET", "/status", nil))
+		})
+		e.LogReq(httptest.NewRequest("GET", "/after", nil))
+
+		if e.Pass() {
+			t.Errorf("Expected an unmatched request inside DoStrict to fail")
+		}
+
+		want := "Expectations\n\tGET /status - passed\n\tGET /nested - passed\nUnmatched Requests\n\tGET /before\n\tGET /during - failed, unexpected request in strict mode\n\tGET /after\n"
+		if got := e.Summary(); got != want {
+			t.Errorf("Summary:\n%s\nexpected\n%s", got, want)
+		}
+	})
+
+	t.Run("DoStrict passes when every request matches", func(t *testing.T) {
+		e := Expecter{}
+		e.ExpectReq("GET", "/status").DoStrict(func() {
+			e.LogReq(httptest.NewRequest("GET", "/status", nil))
+		})
+		e.LogReq(httptest.NewRequest("GET", "/after", nil))
+		assertPassed(t, 1, &e)
+	})
+}
+
+func TestRespondToUnmatched(t *testing.T) {
+	server := NewServer(&testing.T{}, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		io.WriteString(rw, "fallback")
+	}))
+	server.Strict()
+	server.RespondToUnmatched(http.StatusNotImplemented)
+	server.ExpectReq("GET", "/status")
+
+	resp, err := http.Get(server.URL + "/debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Errorf("Expected unmatched request to receive status 501, got %d", resp.StatusCode)
+	}
+	if string(body) != "hex: no expectation matched GET /debug\n" {
+		t.Errorf("Unexpected response body %q", body)
+	}
+
+	// Requests that match an expectation still reach the handler
+	resp, err = http.Get(server.URL + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ = io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "fallback" {
+		t.Errorf("Expected matched request to reach the handler, got %d %q", resp.StatusCode, body)
+	}
+}
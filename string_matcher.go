@@ -3,28 +3,40 @@ package hex
 import (
 	"fmt"
 	"regexp"
+	"strings"
 )
 
 // StringMatcher is used for matching parts of a request that can only ever be strings, such as the HTTP Method,
 // path, query string/header/form keys (not values, which can be arrays), etc.
 type StringMatcher func(string) bool
 
-// makeStringMatcher takes as input a string, regular expression, or StringMatcher and returns a StringMatcher
+// makeStringMatcher takes as input a string, Fold, regular expression, path template, LogicalMatcher or StringMatcher
+// and returns a StringMatcher
 func makeStringMatcher(arg interface{}) (stringMatcher, error) {
 	if str, ok := arg.(string); ok {
 		return &stringLiteralMatcher{str: str}, nil
+	} else if fold, ok := arg.(Fold); ok {
+		return &stringFoldMatcher{str: string(fold)}, nil
+	} else if tmpl, ok := arg.(Path); ok {
+		return compilePathTemplate(string(tmpl))
+	} else if l, ok := arg.(LogicalMatcher); ok {
+		return makeLogicalStringMatcher(l)
 	} else if re, ok := arg.(*regexp.Regexp); ok {
 		return &stringRegexMatcher{pattern: re}, nil
 	} else if sm, ok := arg.(func(string) bool); ok {
 		return &funcStringMatcher{fn: sm}, nil
+	} else if sm, ok := arg.(StringMatcher); ok {
+		return &funcStringMatcher{fn: sm}, nil
 	} else if c, ok := arg.(MatchConst); ok {
 		if c == Any {
 			return &funcStringMatcher{
-				fn: func(string) bool { return true },
+				fn:   func(string) bool { return true },
+				desc: "<any>",
 			}, nil
 		} else if c == None {
 			return &funcStringMatcher{
-				fn: func(string) bool { return false },
+				fn:   func(string) bool { return false },
+				desc: "<none>",
 			}, nil
 		}
 	}
@@ -71,6 +83,24 @@ func (s *stringLiteralMatcher) match(candidate string) bool {
 	return s.str == candidate
 }
 
+// Fold matches strings case-insensitively, ie hex.Fold("bearer abc") matches "Bearer abc" and "BEARER ABC". Strings
+// are compared using strings.EqualFold.
+type Fold string
+
+type stringFoldMatcher struct {
+	str string
+}
+
+var _ stringMatcher = &stringFoldMatcher{}
+
+func (s *stringFoldMatcher) String() string {
+	return s.str + " (any case)"
+}
+
+func (s *stringFoldMatcher) match(candidate string) bool {
+	return strings.EqualFold(s.str, candidate)
+}
+
 type stringRegexMatcher struct {
 	pattern *regexp.Regexp
 }
@@ -98,7 +128,8 @@ func (*noStringMatcher) String() string {
 }
 
 type funcStringMatcher struct {
-	fn StringMatcher
+	fn   StringMatcher
+	desc string
 }
 
 var _ stringMatcher = &funcStringMatcher{}
@@ -108,5 +139,8 @@ func (s *funcStringMatcher) match(candidate string) bool {
 }
 
 func (s *funcStringMatcher) String() string {
+	if s.desc != "" {
+		return s.desc
+	}
 	return "custom string matching function"
 }
@@ -33,9 +33,20 @@ func TestStringMatcher(t *testing.T) {
 		{None, "", false},
 		{None, "1023", false},
 
+		// Case-insensitive matching
+		{Fold("foo"), "FOO", true},
+		{Fold("foo"), "Foo", true},
+		{Fold("foo"), "food", false},
+
+		// Path templates
+		{Path("/users/{id}"), "/users/123", true},
+		{Path("/users/{id:[0-9]+}"), "/users/bob", false},
+
 		// Custom matching funcs
 		{func(s string) bool { return s == "foo" }, "foo", true},
 		{func(s string) bool { return s == "foo" }, "bar", false},
+		{StringMatcher(func(s string) bool { return s == "foo" }), "foo", true},
+		{StringMatcher(func(s string) bool { return s == "foo" }), "bar", false},
 	}
 
 	for _, tc := range testCases {
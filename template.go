@@ -0,0 +1,154 @@
+package hex
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// TemplateData is the data available to templates given to RespondWithTemplate
+type TemplateData struct {
+	// Method is the request's HTTP method
+	Method string
+
+	// Path is the request's path, not including the query string
+	Path string
+
+	// Params holds the parameters captured by the expectation's path template, see Path and PathParams
+	Params map[string]string
+
+	// Query holds the request's query string parameters
+	Query url.Values
+
+	// Header holds the request's headers
+	Header http.Header
+
+	// Form holds the request's form-encoded body parameters
+	Form url.Values
+
+	// JSON holds the request's body decoded as JSON, or nil if the body is not valid JSON
+	JSON interface{}
+
+	// Body is the request's raw body
+	Body string
+}
+
+// RespondWithTemplate responds with the given status, and a body rendered from tmpl using text/template. The template
+// is executed with a TemplateData describing the request, and may use these functions in addition to the built-in
+// functions of text/template:
+//
+//   uuid            a random version 4 UUID
+//   now             the current time, as a time.Time
+//   counter "name"  a number that starts at 1 and increments each time the counter is used by this template
+//   json value      value encoded as JSON
+//
+// For example:
+//
+//   server.ExpectReq("POST", "/users").RespondWithTemplate(201,
+//     `{"id": "{{uuid}}", "name": {{json .JSON.name}}, "created_at": "{{now.Format "2006-01-02"}}"}`)
+//
+//   server.ExpectReq("GET", hex.Path("/users/{id}")).RespondWithTemplate(200,
+//     `{"id": {{.Params.id}}, "request": {{counter "users"}}}`)
+//
+// The Content-Type is application/json if the rendered body is valid JSON, and is otherwise detected from the body.
+// RespondWithTemplate panics if tmpl cannot be parsed. If the template fails to execute, the expectation fails and the
+// request receives a 500 response.
+func (e *Expectation) RespondWithTemplate(status int, tmpl string) *Expectation {
+	r := &templateResponder{counters: map[string]int{}}
+
+	t, err := template.New("RespondWithTemplate").Funcs(template.FuncMap{
+		"uuid":    newUUID,
+		"now":     time.Now,
+		"counter": r.counter,
+		"json":    encodeJSON,
+	}).Parse(tmpl)
+	if err != nil {
+		panic(fmt.Sprintf("RespondWithTemplate: %s", err.Error()))
+	}
+	r.template = t
+
+	return e.RespondWithFn(func(rw http.ResponseWriter, req *http.Request) {
+		buf := &bytes.Buffer{}
+		if err := r.template.Execute(buf, newTemplateData(req)); err != nil {
+			e.recordFailure("cannot render template: %s", err.Error())
+			http.Error(rw, "hex: cannot render template: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if json.Valid(buf.Bytes()) {
+			rw.Header().Set("Content-Type", "application/json")
+		} else {
+			rw.Header().Set("Content-Type", http.DetectContentType(buf.Bytes()))
+		}
+		rw.WriteHeader(status)
+		if _, err := rw.Write(buf.Bytes()); err != nil {
+			panic("Failed to write response in RespondWithTemplate")
+		}
+	})
+}
+
+type templateResponder struct {
+	template *template.Template
+
+	// mu guards counters, as requests may be served concurrently
+	mu       sync.Mutex
+	counters map[string]int
+}
+
+func (r *templateResponder) counter(name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counters[name]++
+	return r.counters[name]
+}
+
+func newTemplateData(req *http.Request) *TemplateData {
+	body := RequestBody(req)
+
+	data := &TemplateData{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Params: PathParams(req),
+		Query:  req.URL.Query(),
+		Header: req.Header,
+		Body:   string(body),
+	}
+
+	if value, err := decodeJSON(body); err == nil {
+		data.JSON = value
+	}
+
+	rewindBody(req)
+	if err := req.ParseForm(); err == nil {
+		data.Form = req.PostForm
+	}
+	// Leave the body readable by any handler that runs after this one
+	rewindBody(req)
+
+	return data
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+func encodeJSON(value interface{}) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
@@ -0,0 +1,138 @@
+package hex
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRespondWithTemplate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		tmpl        string
+		req         *http.Request
+		contentType string
+		body        string
+	}{
+		{
+			name:        "method and path",
+			tmpl:        `{{.Method}} {{.Path}}`,
+			req:         httptest.NewRequest("GET", "/users?page=2", nil),
+			contentType: "text/plain; charset=utf-8",
+			body:        "GET /users",
+		},
+		{
+			name:        "query and headers",
+			tmpl:        `{"page": {{index .Query.page 0}}, "agent": {{json (.Header.Get "User-Agent")}}}`,
+			req:         newTemplateRequest("GET", "/users?page=2", "", "User-Agent", "test"),
+			contentType: "application/json",
+			body:        `{"page": 2, "agent": "test"}`,
+		},
+		{
+			name:        "form bodies",
+			tmpl:        `{{.Form.Get "name"}}`,
+			req:         newTemplateRequest("POST", "/users", "name=bob", "Content-Type", "application/x-www-form-urlencoded"),
+			contentType: "text/plain; charset=utf-8",
+			body:        "bob",
+		},
+		{
+			name:        "JSON bodies",
+			tmpl:        `{"name": {{json .JSON.name}}, "tags": {{json .JSON.tags}}}`,
+			req:         newTemplateRequest("POST", "/users", `{"name": "bob", "tags": ["a", "b"]}`),
+			contentType: "application/json",
+			body:        `{"name": "bob", "tags": ["a","b"]}`,
+		},
+		{
+			name:        "raw bodies",
+			tmpl:        `<p>{{.Body}}</p>`,
+			req:         newTemplateRequest("POST", "/echo", "hello"),
+			contentType: "text/html; charset=utf-8",
+			body:        "<p>hello</p>",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewServer(t, nil)
+			s.ExpectReq(Any, Any).RespondWithTemplate(202, tc.tmpl)
+
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, tc.req)
+			if rec.Code != 202 {
+				t.Errorf("Status: got %d, want 202", rec.Code)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tc.contentType {
+				t.Errorf("Content-Type: got %q, want %q", got, tc.contentType)
+			}
+			if got := rec.Body.String(); got != tc.body {
+				t.Errorf("Body: got %q, want %q", got, tc.body)
+			}
+		})
+	}
+
+	t.Run("Path params", func(t *testing.T) {
+		s := NewServer(t, nil)
+		s.ExpectReq("GET", Path("/users/{id}")).RespondWithTemplate(200, `user {{.Params.id}}`)
+
+		if got := serve(s, "GET", "/users/123").Body.String(); got != "user 123" {
+			t.Errorf("Body: got %q, want %q", got, "user 123")
+		}
+	})
+
+	t.Run("Counters increment on each use", func(t *testing.T) {
+		s := NewServer(t, nil)
+		s.ExpectReq("GET", "/count").RespondWithTemplate(200, `{{counter "a"}},{{counter "b"}},{{counter "a"}}`).AtLeast(1)
+
+		for _, want := range []string{"1,1,2", "3,2,4"} {
+			if got := serve(s, "GET", "/count").Body.String(); got != want {
+				t.Errorf("Body: got %q, want %q", got, want)
+			}
+		}
+	})
+
+	t.Run("UUIDs and timestamps", func(t *testing.T) {
+		s := NewServer(t, nil)
+		s.ExpectReq("GET", "/id").RespondWithTemplate(200, `{{uuid}} {{now.Year}}`)
+
+		body := serve(s, "GET", "/id").Body.String()
+		if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12} \d{4}$`).MatchString(body) {
+			t.Errorf("Body: got %q, want a UUID and a year", body)
+		}
+	})
+
+	t.Run("Execution errors fail the expectation", func(t *testing.T) {
+		s := &Server{}
+		s.ExpectReq("GET", "/broken").RespondWithTemplate(200, `{{index .Query.missing 0}}`)
+
+		rec := serve(s, "GET", "/broken")
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("Status: got %d, want 500", rec.Code)
+		}
+		if s.Pass() {
+			t.Errorf("Expected a template execution error to fail the expectation")
+		}
+		if summary := s.Summary(); !strings.Contains(summary, "cannot render template") {
+			t.Errorf("Summary: got %q, want it to mention the template error", summary)
+		}
+	})
+
+	t.Run("Invalid templates panic", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil || !strings.HasPrefix(r.(string), "RespondWithTemplate: ") {
+				t.Errorf("Expected RespondWithTemplate to panic, got %v", r)
+			}
+		}()
+		(&Server{}).ExpectReq("GET", "/").RespondWithTemplate(200, `{{.Method`)
+	})
+}
+
+func newTemplateRequest(method, target, body string, header ...string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	return req
+}
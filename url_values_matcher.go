@@ -3,6 +3,8 @@ package hex
 import (
 	"fmt"
 	"net/url"
+	"sort"
+	"strings"
 )
 
 type keyValueMatcher struct {
@@ -10,17 +12,59 @@ type keyValueMatcher struct {
 	value stringMatcher
 }
 
+// urlValuesMatcher matches url.Values against a set of key/value pairs, all of which must match. If op is set, the
+// pairs are ignored and the alternatives in alts are combined by a LogicalMatcher instead.
 type urlValuesMatcher struct {
 	pairs []keyValueMatcher
+	op    logicalOp
+	alts  []urlValuesMatcher
+
+	// tokenize allows values to match by any of their comma-separated tokens, see forHeaders
+	tokenize bool
 }
 
 func (u *urlValuesMatcher) matches(values url.Values) bool {
+	switch u.op {
+	case opAnyOf:
+		for _, alt := range u.alts {
+			if alt.matches(values) {
+				return true
+			}
+		}
+		return false
+	case opAllOf:
+		for _, alt := range u.alts {
+			if !alt.matches(values) {
+				return false
+			}
+		}
+		return true
+	case opNot:
+		return !u.alts[0].matches(values)
+	}
+
 	for _, pair := range u.pairs {
-		for key, values := range values {
-			if pair.key.match(key) {
-				for _, value := range values {
-					if pair.value.match(value) {
-						return true
+		if !pair.matches(values, u.tokenize) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches returns true if at least one key matching the pair's key has a value matching the pair's value. If tokenize
+// is true, a value also matches if any of its comma-separated tokens match.
+func (pair *keyValueMatcher) matches(values url.Values, tokenize bool) bool {
+	for key, values := range values {
+		if pair.key.match(key) {
+			for _, value := range values {
+				if pair.value.match(value) {
+					return true
+				}
+				if tokenize {
+					for _, token := range splitTokens(value) {
+						if pair.value.match(token) {
+							return true
+						}
 					}
 				}
 			}
@@ -29,7 +73,103 @@ func (u *urlValuesMatcher) matches(values url.Values) bool {
 	return false
 }
 
+// relevant returns true if the key is mentioned by the matcher, for describing mismatches. Keys mentioned by a Not are
+// relevant, but are never allowed in exact mode.
+func (u *urlValuesMatcher) relevant(key string) bool {
+	for _, alt := range u.alts {
+		if alt.relevant(key) {
+			return true
+		}
+	}
+	for _, pair := range u.pairs {
+		if pair.key.match(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// allows returns true if the key/value pair is mentioned by the matcher, for exact matching
+func (u *urlValuesMatcher) allows(key, value string) bool {
+	if u.op == opNot {
+		return false
+	}
+	for _, alt := range u.alts {
+		if alt.allows(key, value) {
+			return true
+		}
+	}
+	for _, pair := range u.pairs {
+		if pair.key.match(key) && pair.value.match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// unexpected lists the keys and values that aren't mentioned by the matcher, in order
+func (u *urlValuesMatcher) unexpected(values url.Values) (problems []string) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !u.relevant(key) {
+			problems = append(problems, fmt.Sprintf("unexpected key %q", key))
+			continue
+		}
+		for _, value := range values[key] {
+			if u.allows(key, value) {
+				continue
+			}
+			tokens := splitTokens(value)
+			if !u.tokenize || len(tokens) == 0 {
+				problems = append(problems, fmt.Sprintf("unexpected value %q for key %q", value, key))
+				continue
+			}
+			// A value that doesn't match as a whole is allowed if every one of its tokens is
+			for _, token := range tokens {
+				if !u.allows(key, token) {
+					problems = append(problems, fmt.Sprintf("unexpected value %q for key %q", token, key))
+				}
+			}
+		}
+	}
+	return
+}
+
+// result matches the values, and describes them if they fail to match. In exact mode, values must also not contain
+// any keys or values that aren't mentioned by the matcher.
+func (u *urlValuesMatcher) result(values url.Values, exact bool) matchResult {
+	if !u.matches(values) {
+		return mismatch("%s", u.describe(values))
+	}
+	if exact {
+		if problems := u.unexpected(values); len(problems) > 0 {
+			return mismatch("got %s", strings.Join(problems, ", "))
+		}
+	}
+	return matchSuccess
+}
+
 func makeValueMatcher(arg interface{}) (urlValuesMatcher, error) {
+	// A LogicalMatcher combines other single arguments. This is checked first, as a LogicalMatcher is also a valid
+	// string matcher, but Not should negate the presence of a key rather than match keys with other names.
+	if logical, ok := arg.(LogicalMatcher); ok {
+		c := logical.combination()
+		alts := make([]urlValuesMatcher, 0, len(c.args))
+		for _, arg := range c.args {
+			alt, err := makeValueMatcher(arg)
+			if err != nil {
+				return urlValuesMatcher{}, err
+			}
+			alts = append(alts, alt)
+		}
+		return urlValuesMatcher{op: c.op, alts: alts}, nil
+	}
+
 	// Single argument. If it's a string/regexp/stringmatcher, then we treat it like a key/value matcher,
 	// with "Any" as the value.
 	if m, err := makeStringMatcher(arg); err == nil {
@@ -38,13 +178,19 @@ func makeValueMatcher(arg interface{}) (urlValuesMatcher, error) {
 				{key: m, value: mustMakeStringMatcher(Any)},
 			},
 		}, nil
-	} else if params, ok := arg.(P); ok {
+	} else if params, ok := asParams(arg); ok {
+		// Every pair in a map must match
 		pairs := make([]keyValueMatcher, 0, len(params))
 		for key, value := range params {
-			pairs = append(pairs, keyValueMatcher{
-				key:   mustMakeStringMatcher(key),
-				value: mustMakeStringMatcher(value),
-			})
+			keyMatcher, err := makeStringMatcher(key)
+			if err != nil {
+				return urlValuesMatcher{}, err
+			}
+			valueMatcher, err := makeStringMatcher(value)
+			if err != nil {
+				return urlValuesMatcher{}, err
+			}
+			pairs = append(pairs, keyValueMatcher{key: keyMatcher, value: valueMatcher})
 		}
 		return urlValuesMatcher{pairs: pairs}, nil
 	}
@@ -64,7 +210,15 @@ func makeURLValuesMatcher(args []interface{}) (urlValuesMatcher, error) {
 		return makeValueMatcher(args[0])
 	} else if len(args) == 2 {
 		// Double argument. It's a key/value pair, which is essentially a single element map
-		return makeURLValuesMatcher([]interface{}{P{args[0]: args[1]}})
+		keyMatcher, err := makeStringMatcher(args[0])
+		if err != nil {
+			return urlValuesMatcher{}, err
+		}
+		valueMatcher, err := makeStringMatcher(args[1])
+		if err != nil {
+			return urlValuesMatcher{}, err
+		}
+		return urlValuesMatcher{pairs: []keyValueMatcher{{key: keyMatcher, value: valueMatcher}}}, nil
 	}
 
 	return urlValuesMatcher{}, fmt.Errorf("Cannot use value %v when matching against url.Values", args)
@@ -73,3 +227,30 @@ func makeURLValuesMatcher(args []interface{}) (urlValuesMatcher, error) {
 func (u *urlValuesMatcher) match(value interface{}) bool {
 	return false
 }
+
+// describe summarizes the values that are relevant to the matcher, for explaining why they failed to match
+func (u *urlValuesMatcher) describe(values url.Values) string {
+	if len(values) == 0 {
+		return "got none"
+	}
+
+	var keys, relevant []string
+	for key := range values {
+		keys = append(keys, key)
+		if u.relevant(key) {
+			relevant = append(relevant, key)
+		}
+	}
+	sort.Strings(keys)
+	sort.Strings(relevant)
+
+	if len(relevant) == 0 {
+		return fmt.Sprintf("got no matching keys, found %s", strings.Join(keys, ", "))
+	}
+
+	found := make([]string, len(relevant))
+	for i, key := range relevant {
+		found[i] = fmt.Sprintf("%s=%q", key, values[key])
+	}
+	return "got " + strings.Join(found, ", ")
+}
@@ -58,6 +58,39 @@ func TestURLValuesMatcher_Match(t *testing.T) {
 		{Args{P{"key1": "value1", "key2": "value2"}}, "key1=value1&key2=value2", true},
 		{Args{P{"key1": R(`^value\d+$`), "key2": R(`^value\d+$`)}}, "key1=value1&key2=value1", true},
 		{Args{P{"key1": R(`^value\d+$`), "key2": R(`^value\d+$`)}}, "key1=value&key2=value", false},
+
+		// every pair in a param map must match
+		{Args{P{"q": "x", "page": "2"}}, "q=x&page=2", true},
+		{Args{P{"q": "x", "page": "2"}}, "q=x&page=2&extra=1", true}, // Match, unmentioned keys are ignored
+		{Args{P{"q": "x", "page": "2"}}, "q=x", false},               // Mismatch, page is missing
+		{Args{P{"q": "x", "page": "2"}}, "page=2", false},            // Mismatch, q is missing
+		{Args{P{"q": "x", "page": "2"}}, "q=x&page=3", false},        // Mismatch, page has the wrong value
+		{Args{P{}}, "", true},                                        // Match, an empty map has no conditions
+		{Args{P{}}, "q=x", true},
+
+		// multi-valued keys match if any of their values match
+		{Args{P{"tag": "b"}}, "tag=a&tag=b", true},
+		{Args{P{"tag": "c"}}, "tag=a&tag=b", false},
+		{Args{P{"tag": "a", "id": "1"}}, "tag=a&tag=b&id=2&id=1", true},
+		{Args{P{"tag": "a", "id": "3"}}, "tag=a&tag=b&id=2&id=1", false},
+
+		// regexp keys match if any matching key has a matching value
+		{Args{P{R(`^filter\[\w+\]$`): "on", "page": "1"}}, "filter[a]=off&filter[b]=on&page=1", true},
+		{Args{P{R(`^filter\[\w+\]$`): "on", "page": "1"}}, "filter[a]=off&page=1", false},
+		{Args{P{R(`^filter\[\w+\]$`): "on", "page": "1"}}, "filter[a]=on", false},
+		{Args{P{R(`^a`): "1", R(`^b`): "2"}}, "ab=1&ba=2", true},
+		{Args{P{R(`^a`): "1", R(`^b`): "2"}}, "ab=2&ba=1", false},
+
+		// AnyOf requires at least one of its arguments to match
+		{Args{AnyOf(P{"q": "x"}, P{"page": "2"})}, "q=x", true},
+		{Args{AnyOf(P{"q": "x"}, P{"page": "2"})}, "page=2", true},
+		{Args{AnyOf(P{"q": "x"}, P{"page": "2"})}, "q=y&page=3", false},
+		{Args{AnyOf("q", "search")}, "search=x", true},
+		{Args{AnyOf("q", "search")}, "page=1", false},
+		{Args{AnyOf(P{"q": "x", "page": "2"}, P{"all": "1"})}, "q=x", false},
+		{Args{AnyOf(P{"q": "x", "page": "2"}, P{"all": "1"})}, "q=x&page=2", true},
+		{Args{AnyOf(R(`^key\d$`), AnyOf("other"))}, "other", true},
+		{Args{AnyOf()}, "q=x", false},
 	}
 
 	for _, tc := range testCases {
@@ -81,3 +114,16 @@ func TestURLValuesMatcher_Match(t *testing.T) {
 	}
 
 }
+
+func TestURLValuesMatcherInvalidArgs(t *testing.T) {
+	for _, arg := range []interface{}{
+		123,
+		P{123: "x"},
+		P{"x": 123},
+		AnyOf("x", 123),
+	} {
+		if _, err := makeURLValuesMatcher([]interface{}{arg}); err == nil {
+			t.Errorf("Expected an error for %v", arg)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package hex
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WebSocketExpectation is an Expectation for a WebSocket connection, with a scripted conversation. See ExpectWebSocket.
+type WebSocketExpectation struct {
+	*Expectation
+
+	steps []webSocketStep
+}
+
+// ExpectWebSocket expects a WebSocket upgrade request for path, which may be anything accepted by ExpectReq, and
+// accepts the upgrade. The conversation that follows is scripted with Receive and Send, and steps are carried out in
+// the order they're defined:
+//
+//   server.ExpectWebSocket("/chat").
+//     ReceiveJSON(hex.P{"type": "join", "room": "lobby"}).
+//     SendJSON(hex.P{"type": "joined"}).
+//     Receive(hex.R(`^ping \d+$`)).
+//     Send("pong")
+//
+// Once the script is complete, the server closes the connection. Messages that don't match the script, messages left
+// unsent by the client when it closes the connection, and messages received after the end of the script fail the
+// expectation, and are reported in the summary. Ping frames are answered automatically.
+//
+// When the test ends or the Server is closed, conversations still in progress are ended, and their failures are
+// included in the report.
+//
+// Conditions like WithHeader can be added after the conversation has been scripted.
+func (e *Expecter) ExpectWebSocket(path interface{}) *WebSocketExpectation {
+	ws := &WebSocketExpectation{
+		Expectation: e.ExpectReq("GET", path),
+	}
+	ws.addMatcher(&webSocketUpgradeMatcher{})
+	ws.RespondWithFn(ws.serve)
+	return ws
+}
+
+// Receive expects the client's next message to match want, which may be anything accepted by ExpectReq's path, such as
+// a string, hex.R, hex.AnyOf or a func(string) bool. Text and binary messages are both matched as strings.
+func (w *WebSocketExpectation) Receive(want interface{}) *WebSocketExpectation {
+	m, err := makeStringMatcher(want)
+	if err != nil {
+		panic(fmt.Sprintf("Receive: %s", err.Error()))
+	}
+	return w.addStep(webSocketStep{
+		desc: m.String(),
+		receive: func(message []byte) error {
+			if !m.match(string(message)) {
+				return fmt.Errorf("got %q", message)
+			}
+			return nil
+		},
+	})
+}
+
+// ReceiveJSON expects the client's next message to be JSON matching want, which is matched like the body given to
+// WithJSONBody
+func (w *WebSocketExpectation) ReceiveJSON(want interface{}) *WebSocketExpectation {
+	m, err := makeJSONMatcher(want, false)
+	if err != nil {
+		panic(fmt.Sprintf("ReceiveJSON: %s", err.Error()))
+	}
+	return w.addStep(webSocketStep{
+		desc: fmt.Sprintf("JSON %s", m.String()),
+		receive: func(message []byte) error {
+			value, err := decodeJSON(message)
+			if err != nil {
+				return fmt.Errorf("got invalid JSON %q", message)
+			}
+			return m.matchJSON("$", value)
+		},
+	})
+}
+
+// Send sends a text message to the client
+func (w *WebSocketExpectation) Send(message string) *WebSocketExpectation {
+	return w.addStep(webSocketStep{opcode: wsText, send: []byte(message)})
+}
+
+// SendBinary sends a binary message to the client
+func (w *WebSocketExpectation) SendBinary(message []byte) *WebSocketExpectation {
+	return w.addStep(webSocketStep{opcode: wsBinary, send: message})
+}
+
+// SendJSON sends v encoded as JSON to the client, in a text message. It panics if v cannot be encoded.
+func (w *WebSocketExpectation) SendJSON(v interface{}) *WebSocketExpectation {
+	message, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("SendJSON: %s", err.Error()))
+	}
+	return w.addStep(webSocketStep{opcode: wsText, send: message})
+}
+
+// webSocketStep is either a message to receive from the client, or a message to send to it
+type webSocketStep struct {
+	desc    string
+	receive func(message []byte) error
+
+	opcode byte
+	send   []byte
+}
+
+func (w *WebSocketExpectation) addStep(step webSocketStep) *WebSocketExpectation {
+	defer w.lock()()
+	w.steps = append(w.steps, step)
+	return w
+}
+
+// serve accepts the upgrade and carries out the scripted conversation
+func (w *WebSocketExpectation) serve(rw http.ResponseWriter, req *http.Request) {
+	if version := req.Header.Get("Sec-WebSocket-Version"); version != "13" {
+		w.recordFailure("unsupported WebSocket version %q", version)
+		rw.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(rw, "hex: unsupported WebSocket version", http.StatusUpgradeRequired)
+		return
+	}
+
+	w.expecter.mu.Lock()
+	steps := w.steps
+	w.expecter.mu.Unlock()
+
+	// The connection is closed when the Server is, in case the client never finishes the conversation
+	attach, forget := w.expecter.trackHung()
+	defer forget()
+	conn, r := hijackBuffered(rw)
+	attach(conn)
+	defer conn.Close()
+
+	fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", webSocketAccept(req.Header.Get("Sec-WebSocket-Key")))
+
+	c := &webSocketConn{conn: conn, r: r}
+	received := 0
+	for _, step := range steps {
+		if step.receive == nil {
+			if err := writeWebSocketFrame(conn, step.opcode, step.send, false); err != nil {
+				w.recordFailure("cannot send WebSocket message %q: %s", step.send, err.Error())
+				return
+			}
+			continue
+		}
+
+		received++
+		message, err := c.readMessage()
+		var protocolErr webSocketProtocolError
+		if errors.As(err, &protocolErr) {
+			w.recordFailure("%s", err.Error())
+			c.close(wsStatusProtocolError)
+			return
+		} else if err != nil {
+			w.recordFailure("missing WebSocket message %d matching %s: %s", received, step.desc, err.Error())
+			return
+		}
+		if err := step.receive(message); err != nil {
+			w.recordFailure("WebSocket message %d: expected %s, %s", received, step.desc, err.Error())
+			c.close(wsStatusPolicyViolation)
+			return
+		}
+	}
+
+	// Send a close frame, and report anything the client sends before its own close frame
+	c.close(wsStatusNormal)
+	conn.SetReadDeadline(time.Now().Add(webSocketCloseTimeout))
+	for {
+		message, err := c.readMessage()
+		if err != nil {
+			var protocolErr webSocketProtocolError
+			if errors.As(err, &protocolErr) {
+				w.recordFailure("%s", err.Error())
+			}
+			return
+		}
+		w.recordFailure("unexpected WebSocket message %q after the end of the conversation", message)
+	}
+}
+
+// webSocketCloseTimeout is how long the server waits for the client's close frame at the end of a conversation
+const webSocketCloseTimeout = time.Second
+
+type webSocketUpgradeMatcher struct{}
+
+var _ matcher = &webSocketUpgradeMatcher{}
+
+func (*webSocketUpgradeMatcher) matches(req *http.Request) matchResult {
+	if !headerHasToken(req.Header, "Upgrade", "websocket") {
+		return mismatch("got no Upgrade: websocket header")
+	}
+	if !headerHasToken(req.Header, "Connection", "upgrade") {
+		return mismatch("got no Connection: upgrade header")
+	}
+	if req.Header.Get("Sec-WebSocket-Key") == "" {
+		return mismatch("got no Sec-WebSocket-Key header")
+	}
+	return matchSuccess
+}
+
+func (*webSocketUpgradeMatcher) String() string {
+	return "WebSocket upgrade"
+}
+
+// headerHasToken returns true if any of the comma-separated values of the header is token, ignoring case
+func headerHasToken(header http.Header, key, token string) bool {
+	for _, value := range header.Values(key) {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// webSocketGUID is appended to the client's key to compute Sec-WebSocket-Accept, see RFC 6455 section 4.2.2
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+func webSocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// WebSocket opcodes, see RFC 6455 section 5.2
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+// WebSocket close status codes, see RFC 6455 section 7.4.1
+const (
+	wsStatusNormal          = 1000
+	wsStatusProtocolError   = 1002
+	wsStatusPolicyViolation = 1008
+)
+
+// webSocketMaxPayload limits the size of frames read from clients
+const webSocketMaxPayload = 16 << 20
+
+type webSocketProtocolError string
+
+func (e webSocketProtocolError) Error() string {
+	return "WebSocket protocol error: " + string(e)
+}
+
+var errWebSocketClosed = errors.New("client closed the connection")
+
+// webSocketConn reads messages from a client, answering control frames as they arrive
+type webSocketConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// readMessage returns the payload of the next text or binary message, reassembling fragmented messages
+func (c *webSocketConn) readMessage() ([]byte, error) {
+	var message []byte
+	fragmented := false
+
+	for {
+		frame, err := readWebSocketFrame(c.r)
+		if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errWebSocketClosed
+		} else if err != nil {
+			return nil, err
+		}
+		if !frame.masked {
+			return nil, webSocketProtocolError("client frame is not masked")
+		}
+
+		switch frame.opcode {
+		case wsPing:
+			writeWebSocketFrame(c.conn, wsPong, frame.payload, false)
+		case wsPong:
+		case wsClose:
+			return nil, errWebSocketClosed
+		case wsText, wsBinary:
+			if fragmented {
+				return nil, webSocketProtocolError("new message started before the previous message finished")
+			}
+			message = frame.payload
+			if frame.fin {
+				return message, nil
+			}
+			fragmented = true
+		case wsContinuation:
+			if !fragmented {
+				return nil, webSocketProtocolError("continuation frame without a message to continue")
+			}
+			message = append(message, frame.payload...)
+			if frame.fin {
+				return message, nil
+			}
+		default:
+			return nil, webSocketProtocolError(fmt.Sprintf("unknown opcode %#x", frame.opcode))
+		}
+	}
+}
+
+// close sends a close frame with the given status code
+func (c *webSocketConn) close(status uint16) {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, status)
+	writeWebSocketFrame(c.conn, wsClose, payload, false)
+}
+
+type webSocketFrame struct {
+	fin     bool
+	opcode  byte
+	masked  bool
+	payload []byte
+}
+
+// readWebSocketFrame reads a single frame, unmasking its payload if necessary
+func readWebSocketFrame(r io.Reader) (*webSocketFrame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, err
+	}
+
+	frame := &webSocketFrame{
+		fin:    head[0]&0x80 != 0,
+		opcode: head[0] & 0x0f,
+		masked: head[1]&0x80 != 0,
+	}
+	if head[0]&0x70 != 0 {
+		return nil, webSocketProtocolError("reserved bits are set")
+	}
+
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if frame.opcode >= wsClose && (length > 125 || !frame.fin) {
+		return nil, webSocketProtocolError("control frames must be unfragmented and at most 125 bytes")
+	}
+	if length > webSocketMaxPayload {
+		return nil, webSocketProtocolError(fmt.Sprintf("frame of %d bytes is too large", length))
+	}
+
+	var mask [4]byte
+	if frame.masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return nil, err
+		}
+	}
+
+	frame.payload = make([]byte, length)
+	if _, err := io.ReadFull(r, frame.payload); err != nil {
+		return nil, err
+	}
+	if frame.masked {
+		for i := range frame.payload {
+			frame.payload[i] ^= mask[i%4]
+		}
+	}
+	return frame, nil
+}
+
+// writeWebSocketFrame writes payload in a single frame. Frames sent by clients must be masked, and frames sent by
+// servers must not be.
+func writeWebSocketFrame(w io.Writer, opcode byte, payload []byte, mask bool) error {
+	frame := []byte{0x80 | opcode, 0}
+
+	switch length := len(payload); {
+	case length <= 125:
+		frame[1] = byte(length)
+	case length <= 0xffff:
+		frame[1] = 126
+		frame = append(frame, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(length))
+	default:
+		frame[1] = 127
+		frame = append(frame, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(length))
+	}
+
+	if !mask {
+		frame = append(frame, payload...)
+		_, err := w.Write(frame)
+		return err
+	}
+
+	var key [4]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return err
+	}
+	frame[1] |= 0x80
+	frame = append(frame, key[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^key[i%4])
+	}
+	_, err := w.Write(frame)
+	return err
+}
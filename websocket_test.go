@@ -0,0 +1,246 @@
+package hex
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// webSocketClient is a minimal client for testing ExpectWebSocket
+type webSocketClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, s *Server, path string) *webSocketClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(s.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", path, key)
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101 Switching Protocols, got %s", resp.Status)
+	}
+	// The example from RFC 6455 section 1.3
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept: got %q", got)
+	}
+	return &webSocketClient{t: t, conn: conn, r: r}
+}
+
+func (c *webSocketClient) send(opcode byte, message string) {
+	c.t.Helper()
+	if err := writeWebSocketFrame(c.conn, opcode, []byte(message), true); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *webSocketClient) receive() *webSocketFrame {
+	c.t.Helper()
+	frame, err := readWebSocketFrame(c.r)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if frame.masked {
+		c.t.Errorf("Expected server frames to be unmasked")
+	}
+	return frame
+}
+
+// finish responds to the server's close frame, and waits for the server to close the connection
+func (c *webSocketClient) finish() {
+	c.t.Helper()
+	frame := c.receive()
+	if frame.opcode != wsClose {
+		c.t.Fatalf("Expected close frame, got opcode %#x", frame.opcode)
+	}
+	c.send(wsClose, string(frame.payload))
+	c.r.ReadByte()
+}
+
+// newUnreportedServer returns a Server which doesn't report failed expectations, for tests that expect failures
+func newUnreportedServer(t *testing.T) *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(s)
+	s.URL = s.Server.URL
+	t.Cleanup(s.Close)
+	return s
+}
+
+// waitForFailure waits for the server to finish handling the connection, which happens asynchronously to the client
+func waitForFailure(t *testing.T, s *Server, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(s.Summary(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected summary to contain %q, got %q", want, s.Summary())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestExpectWebSocket(t *testing.T) {
+	t.Run("Carries out the scripted conversation", func(t *testing.T) {
+		s := NewServer(t, nil)
+		s.ExpectWebSocket("/chat").
+			ReceiveJSON(P{"type": "join"}).
+			SendJSON(P{"type": "joined"}).
+			Receive(R(`^ping \d+$`)).
+			Send("pong")
+
+		c := dialWebSocket(t, s, "/chat")
+		c.send(wsText, `{"type": "join", "room": "lobby"}`)
+		if frame := c.receive(); frame.opcode != wsText || string(frame.payload) != `{"type":"joined"}` {
+			t.Errorf("Expected joined message, got %#x %q", frame.opcode, frame.payload)
+		}
+
+		// Fragmented messages are reassembled, and pings are answered
+		writeFragment(t, c, wsText, false, "ping ")
+		c.send(wsPing, "hello")
+		writeFragment(t, c, wsContinuation, true, "123")
+		if frame := c.receive(); frame.opcode != wsPong || string(frame.payload) != "hello" {
+			t.Errorf("Expected pong, got %#x %q", frame.opcode, frame.payload)
+		}
+		if frame := c.receive(); string(frame.payload) != "pong" {
+			t.Errorf("Expected pong message, got %q", frame.payload)
+		}
+
+		c.finish()
+	})
+
+	t.Run("Reports unexpected messages", func(t *testing.T) {
+		s := newUnreportedServer(t)
+		s.ExpectWebSocket("/chat").Receive("hello")
+
+		c := dialWebSocket(t, s, "/chat")
+		c.send(wsText, "goodbye")
+		waitForFailure(t, s, `WebSocket message 1: expected hello, got "goodbye"`)
+	})
+
+	t.Run("Reports missing messages", func(t *testing.T) {
+		s := newUnreportedServer(t)
+		s.ExpectWebSocket("/chat").Receive("hello").Receive("again")
+
+		c := dialWebSocket(t, s, "/chat")
+		c.send(wsText, "hello")
+		c.send(wsClose, "")
+		waitForFailure(t, s, "missing WebSocket message 2 matching again: client closed the connection")
+	})
+
+	t.Run("Reports messages after the end of the conversation", func(t *testing.T) {
+		s := newUnreportedServer(t)
+		s.ExpectWebSocket("/chat").Send("welcome")
+
+		c := dialWebSocket(t, s, "/chat")
+		c.send(wsText, "extra")
+		waitForFailure(t, s, `unexpected WebSocket message "extra" after the end of the conversation`)
+	})
+
+	t.Run("Matches messages with functions", func(t *testing.T) {
+		s := NewServer(t, nil)
+		s.ExpectWebSocket("/chat").Receive(func(message string) bool { return strings.HasPrefix(message, "hi") })
+
+		c := dialWebSocket(t, s, "/chat")
+		c.send(wsText, "hi there")
+		c.finish()
+	})
+
+	t.Run("Reports conversations in progress when the server closes", func(t *testing.T) {
+		s := newUnreportedServer(t)
+		s.ExpectWebSocket("/chat").Receive("hello")
+
+		dialWebSocket(t, s, "/chat")
+		s.Close()
+
+		// No waiting is needed, as Close waits for the conversation to end
+		if summary := s.Summary(); !strings.Contains(summary, "missing WebSocket message 1 matching hello") {
+			t.Errorf("Expected the missing message to be reported, got %q", summary)
+		}
+	})
+
+	t.Run("Reports conversations still being upgraded when the server closes", func(t *testing.T) {
+		s := newUnreportedServer(t)
+		started := make(chan struct{})
+		s.ExpectWebSocket("/chat").Receive("hello").With(func(req *http.Request) bool {
+			close(started)
+			return true
+		})
+
+		conn, err := net.Dial("tcp", strings.TrimPrefix(s.URL, "http://"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "GET /chat HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+			"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+
+		// Close without waiting for the upgrade to complete
+		<-started
+		s.Close()
+
+		if summary := s.Summary(); !strings.Contains(summary, "missing WebSocket message 1 matching hello") {
+			t.Errorf("Expected the missing message to be reported, got %q", summary)
+		}
+	})
+
+	t.Run("Reports unmasked frames", func(t *testing.T) {
+		s := newUnreportedServer(t)
+		s.ExpectWebSocket("/chat").Receive("hello")
+
+		c := dialWebSocket(t, s, "/chat")
+		writeWebSocketFrame(c.conn, wsText, []byte("hello"), false)
+		waitForFailure(t, s, "WebSocket protocol error: client frame is not masked")
+	})
+
+	t.Run("Does not match requests without an upgrade", func(t *testing.T) {
+		s := &Server{}
+		exp := s.ExpectWebSocket("/chat")
+		serve(s, "GET", "/chat")
+
+		if s.Pass() {
+			t.Errorf("Expected a plain GET not to match a WebSocket expectation")
+		}
+		if result := exp.closest.checks[2].actual; result != "got no Upgrade: websocket header" {
+			t.Errorf("Expected upgrade mismatch, got %q", result)
+		}
+	})
+}
+
+func writeFragment(t *testing.T, c *webSocketClient, opcode byte, fin bool, payload string) {
+	t.Helper()
+	var frame []byte
+	if err := writeWebSocketFrame(writerFunc(func(p []byte) (int, error) {
+		frame = append(frame, p...)
+		return len(p), nil
+	}), opcode, []byte(payload), true); err != nil {
+		t.Fatal(err)
+	}
+	if !fin {
+		frame[0] &^= 0x80
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
@@ -2,21 +2,29 @@ package hex
 
 import "net/http"
 
+// MatchFn is a condition on a whole request, which returns true if the request matched and false otherwise.
+// It can be given to ExpectReq or With.
+type MatchFn func(req *http.Request) bool
+
 type withMatcher struct {
-	fn func(*http.Request) bool
+	fn MatchFn
 }
 
 var _ matcher = &withMatcher{}
 
-func (w *withMatcher) matches(req *http.Request) bool {
-	return w.fn(req)
+func (w *withMatcher) matches(req *http.Request) matchResult {
+	if w.fn(req) {
+		return matchSuccess
+	}
+	return mismatch("custom matcher returned false")
 }
 
 func (w *withMatcher) String() string {
 	return "<custom With matcher>"
 }
 
-// With adds a generic condition callback that must return true if the request matched, and false otherwise
-func (e *Expectation) With(fn func(req *http.Request) bool) {
-	e.matchers = append(e.matchers, &withMatcher{fn: fn})
+// With adds a generic condition callback that must return true if the request matched, and false otherwise.
+// Callbacks are called without the Expecter's lock held, so they may call methods such as Summary.
+func (e *Expectation) With(fn func(req *http.Request) bool) *Expectation {
+	return e.addMatcher(&withMatcher{fn: fn})
 }